@@ -0,0 +1,307 @@
+package certgo
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+const (
+	// DefaultAgentHealthAddr is the address of the health endpoint when agent.health_addr is not set.
+	DefaultAgentHealthAddr = "127.0.0.1:8787"
+
+	defaultAgentInterval    = time.Hour
+	defaultAgentRenewBefore = 30 * 24 * time.Hour
+	agentHookTimeout        = time.Minute
+)
+
+// AgentCertificate is the state of a certificate after a check of the agent.
+type AgentCertificate struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	NotAfter time.Time `json:"not_after"`
+	Renewed  bool      `json:"renewed"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// AgentStatus is the result of the last check of the agent, served by its health endpoint.
+type AgentStatus struct {
+	// Healthy is false before the first check and when a certificate or a hook failed.
+	Healthy      bool               `json:"healthy"`
+	LastCheck    time.Time          `json:"last_check"`
+	NextCheck    time.Time          `json:"next_check"`
+	Certificates []AgentCertificate `json:"certificates"`
+	Errors       []string           `json:"errors,omitempty"`
+}
+
+// Agent renews the certificates of a configuration in the background.
+type Agent struct {
+	yamlPath    string
+	interval    time.Duration
+	jitter      time.Duration
+	renewBefore time.Duration
+	healthAddr  string
+
+	mu     sync.Mutex
+	status AgentStatus
+}
+
+// NewAgent returns an agent for the configuration file at yamlPath, set up by its agent section.
+// The certificates are read again from the file at every check.
+func NewAgent(yamlPath string) (*Agent, error) {
+	cfg, err := ReadConfig(yamlPath)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Agent{
+		yamlPath:    yamlPath,
+		interval:    defaultAgentInterval,
+		renewBefore: defaultAgentRenewBefore,
+		healthAddr:  DefaultAgentHealthAddr,
+	}
+	for _, d := range []struct {
+		field string
+		value string
+		dst   *time.Duration
+	}{{"interval", cfg.Agent.Interval, &a.interval}, {"jitter", cfg.Agent.Jitter, &a.jitter}, {"renew_before", cfg.Agent.RenewBefore, &a.renewBefore}} {
+		if d.value == "" {
+			continue
+		}
+		if *d.dst, err = util.ParseDayDuration(d.value); err != nil {
+			return nil, fmt.Errorf("agent %s: %w", d.field, err)
+		}
+	}
+	if a.interval == 0 {
+		return nil, errors.New("agent interval must be positive")
+	}
+	if cfg.Agent.Jitter == "" {
+		a.jitter = a.interval / 10
+	}
+	if cfg.Agent.HealthAddr != "" {
+		if err := a.SetHealthAddr(cfg.Agent.HealthAddr); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// SetHealthAddr sets the address of the health endpoint, which must be a loopback address.
+func (a *Agent) SetHealthAddr(addr string) error {
+	if err := validHealthAddr(addr); err != nil {
+		return err
+	}
+	a.healthAddr = addr
+	return nil
+}
+
+func validHealthAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid health address: %q, expected host:port", addr)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("invalid health address: %q, the host must be localhost or a loopback address", addr)
+	}
+	return nil
+}
+
+// Run serves the health endpoint and checks the certificates every interval plus a random jitter
+// until ctx is done. The first check runs immediately.
+func (a *Agent) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", a.healthAddr)
+	if err != nil {
+		util.Logger().Error("failed to listen on the health address", "addr", a.healthAddr, "error", err)
+		return err
+	}
+	srv := &http.Server{Handler: a, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			util.Logger().Error("health endpoint stopped", "addr", a.healthAddr, "error", err)
+		}
+	}()
+	defer func() {
+		_ = srv.Close()
+	}()
+	util.Logger().Info("agent started", "yaml", a.yamlPath, "interval", a.interval, "jitter", a.jitter, "renew_before", a.renewBefore, "health", a.healthAddr)
+
+	for {
+		a.Check(ctx)
+
+		delay := a.interval
+		if a.jitter > 0 {
+			delay += rand.N(a.jitter)
+		}
+		a.mu.Lock()
+		a.status.NextCheck = util.Now().Add(delay)
+		a.mu.Unlock()
+		util.Logger().Debug("next check scheduled", "in", delay)
+
+		select {
+		case <-ctx.Done():
+			util.Logger().Info("agent stopped")
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Check renews every certificate of the configuration that expires within the renewal window, in
+// issuer order, then runs the hooks of the renewed certificates.
+func (a *Agent) Check(ctx context.Context) AgentStatus {
+	status := AgentStatus{Healthy: true, LastCheck: util.Now(), Certificates: []AgentCertificate{}}
+	fail := func(err error) {
+		status.Healthy = false
+		status.Errors = append(status.Errors, err.Error())
+	}
+
+	var hooks []model.AgentHook
+	var renewed []string
+	cfg, err := ReadConfig(a.yamlPath)
+	if err == nil {
+		hooks = cfg.Agent.Hooks
+		var certs []initCert
+		if certs, err = initOrder(cfg.CA); err == nil {
+			for _, c := range certs {
+				item := a.renew(c)
+				if item.Renewed {
+					renewed = append(renewed, c.name)
+				}
+				if item.Error != "" {
+					status.Healthy = false
+				}
+				status.Certificates = append(status.Certificates, item)
+			}
+		}
+	}
+	if err != nil {
+		util.Logger().Error("failed to read the configuration", "yaml", a.yamlPath, "error", err)
+		fail(err)
+	}
+
+	for i, hook := range hooks {
+		names := hookCertificates(hook, renewed)
+		if len(names) == 0 {
+			continue
+		}
+		if err := runAgentHook(ctx, hook, names); err != nil {
+			util.Logger().Error("agent hook failed", "hook", i+1, "error", err)
+			fail(fmt.Errorf("hook %d: %w", i+1, err))
+		}
+	}
+
+	util.Logger().Info("agent check done", "certificates", len(status.Certificates), "renewed", len(renewed), "healthy", status.Healthy)
+	a.mu.Lock()
+	status.NextCheck = a.status.NextCheck
+	a.status = status
+	a.mu.Unlock()
+	return status
+}
+
+func (a *Agent) renew(c initCert) AgentCertificate {
+	item := AgentCertificate{Name: c.name, Path: c.cfg.CertFilePath}
+	opts, err := renewOptions(c)
+	if err == nil {
+		opts.Window = a.renewBefore
+		var cert *x509.Certificate
+		if cert, item.Renewed, err = RenewCertificate(c.cfg.CertFilePath, opts); err == nil {
+			item.NotAfter = cert.NotAfter
+		}
+	}
+	if err != nil {
+		util.Logger().Error("failed to renew certificate", "name", c.name, "path", c.cfg.CertFilePath, "error", err)
+		item.Error = err.Error()
+	}
+	return item
+}
+
+// Status returns the result of the last check.
+func (a *Agent) Status() AgentStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.status
+}
+
+// ServeHTTP serves the status as JSON, with status code 200 when healthy and 503 otherwise.
+func (a *Agent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	status := a.Status()
+	if status.Certificates == nil {
+		status.Certificates = []AgentCertificate{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+// hookCertificates returns the renewed certificates the hook is run for.
+func hookCertificates(hook model.AgentHook, renewed []string) []string {
+	if len(hook.Certificates) == 0 {
+		return renewed
+	}
+	var names []string
+	for _, name := range renewed {
+		for _, c := range hook.Certificates {
+			if c == name {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// runAgentHook runs the command of the hook with CERTGO_RENEWED set to the renewed certificates,
+// or sends SIGHUP to the process whose pid is in its pid file.
+func runAgentHook(ctx context.Context, hook model.AgentHook, renewed []string) error {
+	switch {
+	case len(hook.Exec) != 0:
+		ctx, cancel := context.WithTimeout(ctx, agentHookTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, hook.Exec[0], hook.Exec[1:]...)
+		cmd.Env = append(os.Environ(), "CERTGO_RENEWED="+strings.Join(renewed, ","))
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %w: %s", hook.Exec[0], err, strings.TrimSpace(string(out)))
+		}
+		util.Logger().Info("agent hook run", "exec", hook.Exec[0], "renewed", renewed)
+	case hook.SignalPidFile != "":
+		data, err := os.ReadFile(hook.SignalPidFile)
+		if err != nil {
+			return err
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil || pid <= 0 {
+			return fmt.Errorf("invalid pid in %s", hook.SignalPidFile)
+		}
+		process, err := os.FindProcess(pid)
+		if err != nil {
+			return err
+		}
+		if err := signalReload(process); err != nil {
+			return fmt.Errorf("signal pid %d: %w", pid, err)
+		}
+		util.Logger().Info("agent hook signaled", "pid", pid, "pid_file", hook.SignalPidFile, "renewed", renewed)
+	default:
+		return errors.New("hook has neither exec nor signal_pid_file")
+	}
+	return nil
+}
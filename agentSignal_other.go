@@ -0,0 +1,13 @@
+//go:build !unix
+
+package certgo
+
+import (
+	"errors"
+	"os"
+)
+
+// signalReload fails on platforms without SIGHUP, use an exec hook there.
+func signalReload(process *os.Process) error {
+	return errors.New("signal_pid_file is only supported on unix, use exec instead")
+}
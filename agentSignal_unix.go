@@ -0,0 +1,13 @@
+//go:build unix
+
+package certgo
+
+import (
+	"os"
+	"syscall"
+)
+
+// signalReload sends SIGHUP to process.
+func signalReload(process *os.Process) error {
+	return process.Signal(syscall.SIGHUP)
+}
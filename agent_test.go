@@ -0,0 +1,97 @@
+package certgo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+func TestAgentCheck(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook runs sh")
+	}
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "cfg.yml")
+	hookLog := filepath.Join(dir, "hook.log")
+
+	cfg, err := NewStarterConfig(StarterConfig{
+		Organization: "agent",
+		OutDir:       "./ca",
+		DNSNames:     []string{"localhost"},
+		Leaves:       []StarterLeaf{{Name: "web", Type: constants.CERT_TYPE_SERVER}},
+	})
+	if err != nil {
+		t.Fatalf("TestAgentCheck: %v", err)
+	}
+	// the leaf expires within the renewal window, the CAs do not
+	cfg.Agent = model.Agent{
+		RenewBefore: "400d",
+		HealthAddr:  "localhost:0",
+		Hooks: []model.AgentHook{
+			{Certificates: []string{"web"}, Exec: []string{"sh", "-c", `echo "$CERTGO_RENEWED" >> ` + hookLog}},
+			{Certificates: []string{"root"}, Exec: []string{"false"}},
+		},
+	}
+	if err := util.WriteStructToYamlFile(yamlPath, cfg); err != nil {
+		t.Fatalf("TestAgentCheck: %v", err)
+	}
+	if _, err := InitPKI(yamlPath, ""); err != nil {
+		t.Fatalf("TestAgentCheck: %v", err)
+	}
+
+	a, err := NewAgent(yamlPath)
+	if err != nil {
+		t.Fatalf("TestAgentCheck: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("TestAgentCheck: expected 503 before the first check, got %d", rec.Code)
+	}
+
+	status := a.Check(context.Background())
+	if !status.Healthy || len(status.Certificates) != 3 {
+		t.Fatalf("TestAgentCheck: unexpected status %+v", status)
+	}
+	for _, item := range status.Certificates {
+		if item.Renewed != (item.Name == "web") {
+			t.Fatalf("TestAgentCheck: %s renewed %v", item.Name, item.Renewed)
+		}
+	}
+	data, err := os.ReadFile(hookLog)
+	if err != nil {
+		t.Fatalf("TestAgentCheck: hook was not run: %v", err)
+	}
+	if strings.TrimSpace(string(data)) != "web" {
+		t.Fatalf("TestAgentCheck: hook got %q", data)
+	}
+
+	rec = httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	var served AgentStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &served); err != nil || rec.Code != http.StatusOK || !served.Healthy {
+		t.Fatalf("TestAgentCheck: unexpected health response %d %s", rec.Code, rec.Body.String())
+	}
+
+	// a missing certificate makes the agent unhealthy
+	if err := util.FileDelete(filepath.Join(dir, "ca", "web", "web.cert.pem")); err != nil {
+		t.Fatalf("TestAgentCheck: %v", err)
+	}
+	if status := a.Check(context.Background()); status.Healthy {
+		t.Fatalf("TestAgentCheck: expected unhealthy status for a missing certificate")
+	}
+
+	if err := a.SetHealthAddr("0.0.0.0:8787"); err == nil {
+		t.Fatalf("TestAgentCheck: expected error for a non loopback health address")
+	}
+}
@@ -0,0 +1,203 @@
+package certgo
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Alonza0314/cert-go/util"
+)
+
+// AuditEntry is a line of the audit log. Hash is the SHA-256 of the line written with an empty hash,
+// and PrevHash the hash of the previous line, so changing, inserting or removing a line breaks the chain.
+type AuditEntry struct {
+	Seq uint64 `json:"seq"`
+	HookPayload
+	User     string   `json:"user,omitempty"`
+	Host     string   `json:"host,omitempty"`
+	Command  []string `json:"command,omitempty"`
+	PrevHash string   `json:"prev_hash"`
+	Hash     string   `json:"hash"`
+}
+
+// AuditVerification is the result of a successful VerifyAuditLog.
+type AuditVerification struct {
+	Entries  uint64
+	LastHash string
+}
+
+var (
+	auditLog   atomic.Pointer[string]
+	auditMutex sync.Mutex
+)
+
+// SetAuditLog appends every key generation, csr creation, signing and renewal to the hash-chained
+// audit log at path, in JSON lines. An empty path disables the audit log, the default.
+// Each append holds an exclusive lock on the file, so processes such as the agent and the command line
+// tool can share an audit log.
+func SetAuditLog(path string) {
+	auditLog.Store(&path)
+}
+
+// writeAuditEntry appends the event of payload to the audit log, if any.
+func writeAuditEntry(payload HookPayload) error {
+	path := auditLog.Load()
+	if path == nil || *path == "" {
+		return nil
+	}
+
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+
+	if !util.FileDirExists(*path) {
+		if err := os.MkdirAll(filepath.Dir(*path), util.KeyDirMode); err != nil {
+			util.Logger().Error("failed to create directory", "path", filepath.Dir(*path), "error", err)
+			return err
+		}
+	}
+	f, err := os.OpenFile(*path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		util.Logger().Error("failed to open audit log", "path", *path, "error", err)
+		return err
+	}
+	defer f.Close()
+
+	// the last entry must not change until this one is appended
+	if err := util.FileLock(f); err != nil {
+		util.Logger().Error("failed to lock audit log", "path", *path, "error", err)
+		return err
+	}
+	defer func() {
+		_ = util.FileUnlock(f)
+	}()
+
+	last, err := lastAuditEntry(f)
+	if err != nil {
+		util.Logger().Error("failed to read audit log", "path", *path, "error", err)
+		return fmt.Errorf("audit log %s: %w", *path, err)
+	}
+
+	entry := AuditEntry{
+		Seq:         last.Seq + 1,
+		HookPayload: payload,
+		Command:     os.Args,
+		PrevHash:    last.Hash,
+	}
+	entry.Time = entry.Time.UTC()
+	if u, err := user.Current(); err == nil {
+		entry.User = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		entry.Host = host
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line, _ = setAuditHash(line)
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		util.Logger().Error("failed to write audit log", "path", *path, "error", err)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		util.Logger().Error("failed to write audit log", "path", *path, "error", err)
+		return err
+	}
+	util.Logger().Debug("audit entry written", "path", *path, "seq", entry.Seq, "event", string(entry.Event))
+	return nil
+}
+
+// lastAuditEntry returns the last entry of the audit log, the zero entry when it is empty.
+func lastAuditEntry(r io.Reader) (AuditEntry, error) {
+	var last []byte
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) != 0 {
+			last = line
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return AuditEntry{}, err
+		}
+	}
+
+	var entry AuditEntry
+	if last == nil {
+		return entry, nil
+	}
+	if err := json.Unmarshal(last, &entry); err != nil {
+		return entry, fmt.Errorf("invalid last entry: %w", err)
+	}
+	return entry, nil
+}
+
+// setAuditHash returns line, a marshaled AuditEntry ending with its hash field, with the hash computed
+// over line with an empty hash, and the hash found in line.
+func setAuditHash(line []byte) ([]byte, string) {
+	const empty = `"hash":""}`
+	i := bytes.LastIndex(line, []byte(`"hash":"`))
+	if i < 0 {
+		return line, ""
+	}
+	found := string(bytes.TrimSuffix(line[i+len(`"hash":"`):], []byte(`"}`)))
+
+	unhashed := append(append([]byte{}, line[:i]...), empty...)
+	sum := sha256.Sum256(unhashed)
+	hash := hex.EncodeToString(sum[:])
+	return append(unhashed[:i], fmt.Sprintf(`"hash":%q}`, hash)...), found
+}
+
+// VerifyAuditLog checks the hash chain of the audit log at path and returns the error of the first
+// broken line. Removing the last lines keeps the chain valid, compare LastHash with a copy kept aside.
+func VerifyAuditLog(path string) (AuditVerification, error) {
+	var result AuditVerification
+	f, err := os.Open(path)
+	if err != nil {
+		util.Logger().Error("failed to open audit log", "path", path, "error", err)
+		return result, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && len(line) == 0 {
+			break
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return result, err
+		}
+		line = bytes.TrimSuffix(line, []byte("\n"))
+
+		var entry AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return result, fmt.Errorf("line %d: invalid entry: %w", lineNo, err)
+		}
+		if entry.Seq != result.Entries+1 {
+			return result, fmt.Errorf("line %d: sequence %d, expected %d", lineNo, entry.Seq, result.Entries+1)
+		}
+		if entry.PrevHash != result.LastHash {
+			return result, fmt.Errorf("line %d: previous hash does not match the hash of line %d", lineNo, lineNo-1)
+		}
+		expected, found := setAuditHash(line)
+		if found != entry.Hash || !bytes.Equal(expected, line) {
+			return result, fmt.Errorf("line %d: hash does not match the entry", lineNo)
+		}
+		result.Entries, result.LastHash = entry.Seq, entry.Hash
+	}
+	return result, nil
+}
@@ -0,0 +1,134 @@
+package certgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Alonza0314/cert-go/constants"
+)
+
+func TestAuditLog(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "cfg.yml")
+	logPath := filepath.Join(dir, "audit", "audit.jsonl")
+	SetAuditLog(logPath)
+	defer SetAuditLog("")
+
+	if _, err := WriteStarterConfig(yamlPath, StarterConfig{Organization: "audit", OutDir: "./ca", Leaves: []StarterLeaf{{Name: "web", Type: constants.CERT_TYPE_SERVER}}}, false); err != nil {
+		t.Fatalf("TestAuditLog: %v", err)
+	}
+	if _, err := InitPKI(yamlPath, ""); err != nil {
+		t.Fatalf("TestAuditLog: %v", err)
+	}
+	certPath := filepath.Join(dir, "ca", "web", "web.cert.pem")
+	opts, err := RenewOptionsFromConfig(yamlPath, certPath)
+	if err != nil {
+		t.Fatalf("TestAuditLog: %v", err)
+	}
+	if _, _, err := RenewCertificate(certPath, opts); err != nil {
+		t.Fatalf("TestAuditLog: %v", err)
+	}
+	if _, err := CreatePrivateKey(filepath.Join(dir, "standalone.key.pem"), constants.PRIVATE_KEY_TYPE_ECDSA, false); err != nil {
+		t.Fatalf("TestAuditLog: %v", err)
+	}
+
+	result, err := VerifyAuditLog(logPath)
+	if err != nil {
+		t.Fatalf("TestAuditLog: %v", err)
+	}
+	// root key and cert, intermediate and web key, csr and cert, web renewal, standalone key
+	if result.Entries != 10 {
+		t.Fatalf("TestAuditLog: actual entries %d != expect 10", result.Entries)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("TestAuditLog: %v", err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	var renewed AuditEntry
+	if err := json.Unmarshal(lines[8], &renewed); err != nil {
+		t.Fatalf("TestAuditLog: %v", err)
+	}
+	if renewed.Event != constants.HOOK_EVENT_CERT_RENEWED || renewed.Serial == "" || renewed.Fingerprints["cert"] == "" || len(renewed.Command) == 0 {
+		t.Fatalf("TestAuditLog: unexpected renewal entry %s", lines[8])
+	}
+	var last AuditEntry
+	if err := json.Unmarshal(lines[9], &last); err != nil || last.Hash != result.LastHash || last.PrevHash != renewed.Hash {
+		t.Fatalf("TestAuditLog: unexpected last entry %s", lines[9])
+	}
+
+	testCases := []struct {
+		name   string
+		lines  [][]byte
+		expect string
+	}{
+		{
+			name:   "changed entry",
+			lines:  append(append(append([][]byte{}, lines[:3]...), bytes.Replace(lines[3], []byte("audit"), []byte("forged"), 1)), lines[4:]...),
+			expect: "line 4: hash does not match the entry",
+		},
+		{
+			name:   "removed entry",
+			lines:  append(append([][]byte{}, lines[:5]...), lines[6:]...),
+			expect: "line 6: sequence 7, expected 6",
+		},
+	}
+	for _, testCase := range testCases {
+		tampered := filepath.Join(dir, "tampered.jsonl")
+		if err := os.WriteFile(tampered, append(bytes.Join(testCase.lines, []byte("\n")), '\n'), 0600); err != nil {
+			t.Fatalf("TestAuditLog (%s): %v", testCase.name, err)
+		}
+		if _, err := VerifyAuditLog(tampered); err == nil || !strings.HasPrefix(err.Error(), testCase.expect) {
+			t.Fatalf("TestAuditLog (%s): actual error %v, expect %s", testCase.name, err, testCase.expect)
+		}
+	}
+}
+
+// TestAuditLogHelper appends entries to the audit log of CERTGO_AUDIT_HELPER, it is run by TestAuditLogConcurrent.
+func TestAuditLogHelper(t *testing.T) {
+	path := os.Getenv("CERTGO_AUDIT_HELPER")
+	if path == "" {
+		t.Skip("run by TestAuditLogConcurrent")
+	}
+	SetAuditLog(path)
+	defer SetAuditLog("")
+
+	for i := 0; i < 20; i++ {
+		if err := writeAuditEntry(HookPayload{Event: constants.HOOK_EVENT_KEY_CREATED, Name: "helper"}); err != nil {
+			t.Fatalf("TestAuditLogHelper: %v", err)
+		}
+	}
+}
+
+func TestAuditLogConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	// processes appending at the same time must not reuse a sequence number
+	cmds := make([]*exec.Cmd, 4)
+	for i := range cmds {
+		cmds[i] = exec.Command(os.Args[0], "-test.run=^TestAuditLogHelper$")
+		cmds[i].Env = append(os.Environ(), "CERTGO_AUDIT_HELPER="+path)
+		if err := cmds[i].Start(); err != nil {
+			t.Fatalf("TestAuditLogConcurrent: %v", err)
+		}
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("TestAuditLogConcurrent: %v", err)
+		}
+	}
+
+	result, err := VerifyAuditLog(path)
+	if err != nil {
+		t.Fatalf("TestAuditLogConcurrent: %v", err)
+	}
+	if result.Entries != 80 {
+		t.Fatalf("TestAuditLogConcurrent: expected 80 entries, got %d", result.Entries)
+	}
+}
@@ -6,87 +6,61 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"math/big"
 	"net"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/Alonza0314/cert-go/constants"
 	"github.com/Alonza0314/cert-go/model"
 	"github.com/Alonza0314/cert-go/util"
-	logger "github.com/Alonza0314/logger-go"
 )
 
 func signCertificate(cfg model.Certificate, keyType constants.PrivateKeyType, overwrite bool) (*x509.Certificate, error) {
-	logger.Info("signCertificate", "signing certificate")
+	util.Logger().Debug("signing certificate", "type", cfg.Type, "path", cfg.CertFilePath)
+
+	keyType, err := certificateKeyType(cfg, keyType)
+	if err != nil {
+		return nil, err
+	}
 
 	// check if certificate exists
 	if util.FileExists(cfg.CertFilePath) {
 		if !overwrite {
-			logger.Error("signCertificate", fmt.Sprintf("certificate already exists at %s.", cfg.CertFilePath))
+			util.Logger().Error("certificate already exists", "path", cfg.CertFilePath)
 			return nil, errors.New("certificate already exists")
 		}
-		logger.Warn("signCertificate", "certificate already exists. Overwrite it")
-		if err := util.FileDelete(cfg.CertFilePath); err != nil {
-			logger.Error("signCertificate", "failed to remove existing certificate: "+err.Error())
-			return nil, err
-		}
-	}
-
-	// create certificate template
-	var template *x509.Certificate
-	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
-	if err != nil {
-		logger.Error("signCertificate", err.Error())
-		return nil, err
-	}
-
-	notBefore := time.Now()
-	notAfter := notBefore.AddDate(cfg.ValidityYears, cfg.ValidityMonth, cfg.ValidityDay)
-
-	template = &x509.Certificate{
-		SerialNumber: serialNumber,
-		Subject: pkix.Name{
-			Organization: []string{cfg.Organization},
-			CommonName:   cfg.CommonName,
-		},
-		NotBefore:             notBefore,
-		NotAfter:              notAfter,
-		KeyUsage:              cfg.KeyUsage,
-		ExtKeyUsage:           cfg.ExtKeyUsage,
-		BasicConstraintsValid: true,
-		IsCA:                  cfg.IsCA,
-		DNSNames:              cfg.DNSNames,
-		IPAddresses: func() []net.IP {
-			ips := make([]net.IP, 0)
-			for _, ip := range cfg.IPAddresses {
-				ips = append(ips, net.ParseIP(ip))
-			}
-			return ips
-		}(),
-		URIs: func() []*url.URL {
-			uris := make([]*url.URL, 0)
-			for _, uri := range cfg.URIs {
-				uris = append(uris, &url.URL{Host: uri})
+		if cfg.IsCA || cfg.Type == string(constants.CERT_TYPE_ROOT) {
+			if err := confirmCAOverwrite(cfg.CertFilePath); err != nil {
+				return nil, err
 			}
-			return uris
-		}(),
+		}
+		// replaced only once the new certificate is written
+		util.Logger().Warn("certificate already exists, overwrite it", "path", cfg.CertFilePath)
 	}
 
-	var certBytes []byte
+	var cert *x509.Certificate
 
 	if cfg.Type == string(constants.CERT_TYPE_ROOT) {
 		// root certificate self-signed
 		var parentKey interface{}
 		if !util.FileExists(cfg.KeyFilePath) {
-			logger.Warn("signCertificate", "private key does not exist")
-			parentKey, err = CreatePrivateKey(cfg.KeyFilePath, keyType, overwrite)
+			// a new root key would not match the existing root certificate nor what it issued
+			if util.FileExists(cfg.CertFilePath) {
+				util.Logger().Error("root certificate exists but its private key does not, refuse to create a new one", "path", cfg.KeyFilePath, "cert", cfg.CertFilePath)
+				return nil, fmt.Errorf("private key %s of the existing root certificate does not exist, restore it or remove %s to create a new root", cfg.KeyFilePath, cfg.CertFilePath)
+			}
+			util.Logger().Warn("private key does not exist, creating", "path", cfg.KeyFilePath)
+			parentKey, err = createPrivateKey(cfg.KeyFilePath, keyType, overwrite)
 			if err != nil {
 				return nil, err
 			}
+			if err := recordKeyCreated(cfg, parentKey); err != nil {
+				return nil, err
+			}
 		}
 		if cfg.ParentKey == nil {
 			parentKey, err = util.ReadPrivateKey(cfg.KeyFilePath)
@@ -97,7 +71,7 @@ func signCertificate(cfg model.Certificate, keyType constants.PrivateKeyType, ov
 
 		// check private key type is same as the key type
 		if _, err := util.IsPrivateKeyTypeSame(parentKey, keyType); err != nil {
-			logger.Error("signCertificate", err.Error())
+			util.Logger().Error(err.Error(), "path", cfg.KeyFilePath)
 			return nil, err
 		}
 
@@ -111,26 +85,15 @@ func signCertificate(cfg model.Certificate, keyType constants.PrivateKeyType, ov
 			publicKey = &cfg.ParentKey.(*rsa.PrivateKey).PublicKey
 		}
 
-		// generate subject key id for root certificate(self-signed)
-		if pubKey, ok := publicKey.(*rsa.PublicKey); ok {
-			pkBytes, err := x509.MarshalPKIXPublicKey(pubKey)
-			if err != nil {
-				return nil, err
-			}
-			template.SubjectKeyId = util.HashSHA1(pkBytes)
-			template.AuthorityKeyId = template.SubjectKeyId
-		}
-
-		certBytes, err = x509.CreateCertificate(rand.Reader, template, template, publicKey, cfg.ParentKey)
+		cert, err = IssueCertificate(cfg, publicKey, nil, cfg.ParentKey)
 		if err != nil {
-			logger.Error("signCertificate", err.Error())
 			return nil, err
 		}
 	} else {
 		// intermediate certificate or end-entity certificate
 		var csr *x509.CertificateRequest
 		if !util.FileExists(cfg.CsrFilePath) {
-			logger.Warn("signCertificate", "CSR file does not exist")
+			util.Logger().Warn("csr does not exist, creating", "path", cfg.CsrFilePath)
 			csr, err = CreateCsr(cfg, keyType, overwrite)
 			if err != nil {
 				return nil, err
@@ -144,7 +107,7 @@ func signCertificate(cfg model.Certificate, keyType constants.PrivateKeyType, ov
 		}
 
 		if err := csr.CheckSignature(); err != nil {
-			logger.Error("signCertificate", err.Error())
+			util.Logger().Error("invalid csr signature", "path", cfg.CsrFilePath, "error", err)
 			return nil, err
 		}
 
@@ -160,89 +123,319 @@ func signCertificate(cfg model.Certificate, keyType constants.PrivateKeyType, ov
 			return nil, err
 		}
 
-		// parse parent certificate and get subject key id to set authority key id
-		parentCert, err := x509.ParseCertificate(cfg.ParentCert.Raw)
-		if err != nil {
-			return nil, err
-		}
-		template.AuthorityKeyId = parentCert.SubjectKeyId
-
-		if pubKey, ok := csr.PublicKey.(*rsa.PublicKey); ok {
-			pkBytes, err := x509.MarshalPKIXPublicKey(pubKey)
-			if err != nil {
-				return nil, err
-			}
-			template.SubjectKeyId = util.HashSHA1(pkBytes)
-		}
-
 		// sign certificate with parent certificate
-		certBytes, err = x509.CreateCertificate(rand.Reader, template, cfg.ParentCert, csr.PublicKey, cfg.ParentKey)
+		cert, err = IssueCertificate(cfg, csr.PublicKey, cfg.ParentCert, cfg.ParentKey)
 		if err != nil {
-			logger.Error("signCertificate", err.Error())
 			return nil, err
 		}
 	}
 
+	if err := saveCertificate(cfg, cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// saveCertificate writes cert to the cert path of cfg and records its issuance.
+func saveCertificate(cfg model.Certificate, cert *x509.Certificate) error {
 	// encode certificate to PEM
-	certPEM := pem.EncodeToMemory(&pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: certBytes,
-	})
+	certPEM := util.EncodeCertificatePEM(cert)
 
 	// create directory if it doesn't exist
 	if !util.FileDirExists(cfg.CertFilePath) {
-		logger.Warn("signCertificate", util.FileDir(cfg.CertFilePath)+" directory not exists, creating...")
+		util.Logger().Warn("directory not exists, creating", "path", util.FileDir(cfg.CertFilePath))
 		if err := util.FileDirCreate(cfg.CertFilePath); err != nil {
-			return nil, err
+			return err
 		}
-		logger.Info("signCertificate", util.FileDir(cfg.CertFilePath)+" directory created")
+		util.Logger().Debug("directory created", "path", util.FileDir(cfg.CertFilePath))
 	}
 
 	// write certificate file
-	if err := util.FileWrite(cfg.CertFilePath, certPEM, 0644); err != nil {
+	if err := util.FileWriteAtomic(cfg.CertFilePath, certPEM, 0644); err != nil {
+		return err
+	}
+
+	util.Logger().Info("certificate signed",
+		"type", cfg.Type,
+		"serial", cert.SerialNumber.Text(16),
+		"subject", cert.Subject.String(),
+		"issuer", cert.Issuer.String(),
+		"not_before", cert.NotBefore,
+		"not_after", cert.NotAfter,
+		"path", cfg.CertFilePath,
+	)
+	return recordEvent(cfg.Hooks, newHookPayload(constants.HOOK_EVENT_CERT_ISSUED, cfg, cert, nil))
+}
+
+// IssueCertificate signs a certificate for publicKey in memory, nothing is written to disk.
+// When parentCert is nil the certificate is self-signed and parentKey must be the private key of publicKey.
+// Key usages left empty in cfg get the defaults of its type and of the algorithm of publicKey.
+// A not after beyond the one of parentCert is clamped to it, or rejected when cfg has strict_validity.
+// The certificate is checked against cfg.IssuerPolicy, the policy of the issuer, first.
+func IssueCertificate(cfg model.Certificate, publicKey interface{}, parentCert *x509.Certificate, parentKey interface{}) (*x509.Certificate, error) {
+	template, err := certificateTemplate(cfg, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPolicy(cfg.IssuerPolicy, template, publicKey, cfg.CertFilePath); err != nil {
 		return nil, err
 	}
 
-	logger.Info("signCertificate", cfg.Type+" certificate signed")
+	return signTemplate(template, publicKey, parentCert, parentKey, cfg.StrictValidity, cfg.CertFilePath)
+}
 
-	cert, err := x509.ParseCertificate(certBytes)
+// certificateTemplate returns the template of cfg for publicKey, with the default key usages of its type.
+func certificateTemplate(cfg model.Certificate, publicKey interface{}) (*x509.Certificate, error) {
+	certType := constants.CertType(cfg.Type)
+	if len(cfg.ExtKeyUsage) == 0 && len(cfg.UnknownExtKeyUsage) == 0 {
+		cfg.ExtKeyUsage = defaultExtKeyUsage(certType)
+	}
+	if cfg.KeyUsage == 0 {
+		cfg.KeyUsage = defaultKeyUsage(certType, cfg.IsCA, cfg.ExtKeyUsage, publicKey)
+	}
+	return newCertificateTemplate(cfg)
+}
+
+// signTemplate signs template for publicKey with parentKey, see IssueCertificate. path is only logged.
+func signTemplate(template *x509.Certificate, publicKey interface{}, parentCert *x509.Certificate, parentKey interface{}, strictValidity bool, path string) (*x509.Certificate, error) {
+	// generate subject key id
+	if pubKey, ok := publicKey.(*rsa.PublicKey); ok {
+		pkBytes, err := x509.MarshalPKIXPublicKey(pubKey)
+		if err != nil {
+			return nil, err
+		}
+		template.SubjectKeyId = util.HashSHA1(pkBytes)
+	}
+
+	parent := parentCert
+	if parent == nil {
+		parent = template
+		template.AuthorityKeyId = template.SubjectKeyId
+	} else {
+		template.AuthorityKeyId = parentCert.SubjectKeyId
+		if template.NotAfter.After(parentCert.NotAfter) {
+			if strictValidity {
+				err := fmt.Errorf("not after %s exceeds the not after %s of the issuer", template.NotAfter.Format(time.RFC3339), parentCert.NotAfter.Format(time.RFC3339))
+				util.Logger().Error(err.Error(), "path", path)
+				return nil, err
+			}
+			util.Logger().Warn("not after exceeds the not after of the issuer, clamp it", "path", path, "not_after", template.NotAfter, "issuer_not_after", parentCert.NotAfter)
+			template.NotAfter = parentCert.NotAfter
+			if !template.NotAfter.After(template.NotBefore) {
+				err := fmt.Errorf("not after %s of the issuer is not after not before %s, renew the issuer first", parentCert.NotAfter.Format(time.RFC3339), template.NotBefore.Format(time.RFC3339))
+				util.Logger().Error(err.Error(), "path", path)
+				return nil, err
+			}
+		}
+	}
+
+	certBytes, err := x509.CreateCertificate(util.RandReader(), template, parent, publicKey, util.Signer(parentKey))
 	if err != nil {
-		logger.Error("signCertificate", err.Error())
+		util.Logger().Error("failed to create certificate", "error", err)
 		return nil, err
 	}
 
-	logger.Info("signCertificate", fmt.Sprintf("%s certificate for CN=%s (Org=%s), valid from %s to %s",
-		cfg.Type,
-		cfg.CommonName,
-		cfg.Organization,
-		template.NotBefore.Format("2006-01-02"),
-		template.NotAfter.Format("2006-01-02"),
-	))
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		util.Logger().Error("failed to parse certificate", "error", err)
+		return nil, err
+	}
 	return cert, nil
 }
 
+// validityWindow returns the validity of cfg. It starts at not_before, or now minus backdate, and ends
+// at not_after, or validity_years, validity_month, validity_day and validity after not_before or now.
+func validityWindow(cfg model.Certificate) (time.Time, time.Time, error) {
+	now := util.Now()
+	notBefore := now
+	if cfg.Backdate != "" {
+		backdate, err := util.ParseDuration(cfg.Backdate)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("backdate: %w", err)
+		}
+		notBefore = now.Add(-backdate)
+	}
+	if cfg.NotBefore != "" {
+		t, err := util.ParseTimestamp(cfg.NotBefore)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("not_before: %w", err)
+		}
+		notBefore, now = t, t
+	}
+
+	var notAfter time.Time
+	if cfg.NotAfter != "" {
+		t, err := util.ParseTimestamp(cfg.NotAfter)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("not_after: %w", err)
+		}
+		notAfter = t
+	} else {
+		notAfter = now.AddDate(cfg.ValidityYears, cfg.ValidityMonth, cfg.ValidityDay)
+		if cfg.Validity != "" {
+			validity, err := util.ParseDuration(cfg.Validity)
+			if err != nil {
+				return time.Time{}, time.Time{}, fmt.Errorf("validity: %w", err)
+			}
+			notAfter = notAfter.Add(validity)
+		}
+	}
+
+	if !notAfter.After(notBefore) {
+		return time.Time{}, time.Time{}, fmt.Errorf("not after %s is not after not before %s", notAfter.Format(time.RFC3339), notBefore.Format(time.RFC3339))
+	}
+	return notBefore, notAfter, nil
+}
+
+func newSerialNumber() (*big.Int, error) {
+	serialNumber, err := rand.Int(util.RandReader(), new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		util.Logger().Error("failed to generate serial number", "error", err)
+		return nil, err
+	}
+	return serialNumber, nil
+}
+
+func newCertificateTemplate(cfg model.Certificate) (*x509.Certificate, error) {
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	notBefore, notAfter, err := validityWindow(cfg)
+	if err != nil {
+		util.Logger().Error(err.Error(), "path", cfg.CertFilePath)
+		return nil, err
+	}
+	ips, uris, err := parseAltNames(cfg)
+	if err != nil {
+		util.Logger().Error(err.Error(), "path", cfg.CertFilePath)
+		return nil, err
+	}
+
+	return &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{cfg.Organization},
+			CommonName:   cfg.CommonName,
+		},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              cfg.KeyUsage,
+		ExtKeyUsage:           cfg.ExtKeyUsage,
+		UnknownExtKeyUsage:    cfg.UnknownExtKeyUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  cfg.IsCA,
+		DNSNames:              cfg.DNSNames,
+		IPAddresses:           ips,
+		URIs:                  uris,
+	}, nil
+}
+
+// parseAltNames parses the ip addresses and uris of cfg, uris must have a scheme like spiffe://.
+func parseAltNames(cfg model.Certificate) ([]net.IP, []*url.URL, error) {
+	ips := make([]net.IP, 0, len(cfg.IPAddresses))
+	for _, s := range cfg.IPAddresses {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, nil, fmt.Errorf("invalid ip address %q", s)
+		}
+		ips = append(ips, ip)
+	}
+	uris := make([]*url.URL, 0, len(cfg.URIs))
+	for _, s := range cfg.URIs {
+		uri, err := url.Parse(s)
+		if err != nil || uri.Scheme == "" || strings.ContainsAny(s, " \t") {
+			return nil, nil, fmt.Errorf("invalid uri %q, expected a scheme like spiffe://example.com/ns/prod", s)
+		}
+		uris = append(uris, uri)
+	}
+	return ips, uris, nil
+}
+
 func SignCertificate(certType constants.CertType, keyType constants.PrivateKeyType, yamlPath string, overwrite bool) (*x509.Certificate, error) {
-	var cfg model.CAConfig
-	if err := util.ReadYamlFileToStruct(yamlPath, &cfg); err != nil {
+	cfg, err := ReadConfig(yamlPath)
+	if err != nil {
 		return nil, err
 	}
 
+	var cert model.Certificate
 	switch certType {
 	case constants.CERT_TYPE_ROOT:
-		cfg.CA.Root.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign
-		return signCertificate(cfg.CA.Root, keyType, overwrite)
+		cert = cfg.CA.Root
 	case constants.CERT_TYPE_INTERMEDIATE:
-		cfg.CA.Intermediate.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign
-		return signCertificate(cfg.CA.Intermediate, keyType, overwrite)
+		cert = cfg.CA.Intermediate
+	case constants.CERT_TYPE_SERVER:
+		cert = cfg.CA.Server
+	case constants.CERT_TYPE_CLIENT:
+		cert = cfg.CA.Client
+	case constants.CERT_TYPE_PEER:
+		cert = cfg.CA.Peer
+	default:
+		return nil, errors.New("invalid certificate type")
+	}
+
+	if cert.Type == "" {
+		cert.Type = string(certType)
+	}
+	if err := applyUsage(&cert); err != nil {
+		util.Logger().Error(err.Error(), "type", string(certType))
+		return nil, err
+	}
+	return signCertificate(cert, keyType, overwrite)
+}
+
+// applyUsage parses the key usage names of cfg. The defaults of the certificate
+// type are applied by IssueCertificate when neither names nor values are set.
+func applyUsage(cfg *model.Certificate) error {
+	if len(cfg.KeyUsageNames) != 0 {
+		keyUsage, err := util.ParseKeyUsage(cfg.KeyUsageNames)
+		if err != nil {
+			return err
+		}
+		cfg.KeyUsage = keyUsage
+	}
+
+	if len(cfg.ExtKeyUsageNames) != 0 {
+		extKeyUsage, oids, err := util.ParseExtKeyUsage(cfg.ExtKeyUsageNames)
+		if err != nil {
+			return err
+		}
+		cfg.ExtKeyUsage, cfg.UnknownExtKeyUsage = extKeyUsage, oids
+	}
+	return nil
+}
+
+// defaultExtKeyUsage returns the extended key usage of a certificate type.
+func defaultExtKeyUsage(certType constants.CertType) []x509.ExtKeyUsage {
+	switch certType {
 	case constants.CERT_TYPE_SERVER:
-		cfg.CA.Server.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageContentCommitment
-		cfg.CA.Server.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
-		return signCertificate(cfg.CA.Server, keyType, overwrite)
+		return []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
 	case constants.CERT_TYPE_CLIENT:
-		cfg.CA.Client.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageContentCommitment
-		cfg.CA.Client.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
-		return signCertificate(cfg.CA.Client, keyType, overwrite)
+		return []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	case constants.CERT_TYPE_PEER:
+		return []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	}
+	return nil
+}
+
+// defaultKeyUsage returns the key usage of a certificate for publicKey. CAs get the signing usages,
+// other certificates get digital signature, plus key encipherment for RSA keys used in TLS since
+// only RSA keys can transport a session key. Content commitment is never set by default.
+func defaultKeyUsage(certType constants.CertType, isCA bool, extKeyUsage []x509.ExtKeyUsage, publicKey interface{}) x509.KeyUsage {
+	if isCA || certType == constants.CERT_TYPE_ROOT || certType == constants.CERT_TYPE_INTERMEDIATE {
+		return x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign
 	}
 
-	return nil, errors.New("invalid certificate type")
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, ok := publicKey.(*rsa.PublicKey); !ok {
+		return keyUsage
+	}
+	for _, usage := range extKeyUsage {
+		switch usage {
+		case x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageAny:
+			return keyUsage | x509.KeyUsageKeyEncipherment
+		}
+	}
+	return keyUsage
 }
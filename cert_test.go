@@ -1,9 +1,16 @@
 package certgo
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
 	"crypto/x509"
+	"net"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Alonza0314/cert-go/constants"
 	"github.com/Alonza0314/cert-go/model"
@@ -105,6 +112,9 @@ var testCaseCreateCert = []struct {
 }
 
 func TestSignCertificateECDSA(t *testing.T) {
+	SetCAOverwriteConfirm(func(string) bool { return true })
+	defer SetCAOverwriteConfirm(nil)
+
 	var err error
 	for _, testCase := range testCaseCreateCert {
 		t.Run(testCase.name, func(t *testing.T) {
@@ -192,6 +202,9 @@ func TestSignCertificateECDSA(t *testing.T) {
 }
 
 func TestSignCertificateRSA(t *testing.T) {
+	SetCAOverwriteConfirm(func(string) bool { return true })
+	defer SetCAOverwriteConfirm(nil)
+
 	var err error
 	for _, testCase := range testCaseCreateCert {
 		t.Run(testCase.name, func(t *testing.T) {
@@ -327,4 +340,392 @@ func TestCreateCertKeyTypeUnderRSA(t *testing.T) {
 	if err := util.FileDelete(cfg.CA.Root.CertFilePath); err != nil {
 		t.Fatalf("TestCreateCertKeyTypeUnderRSA: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+var testCaseSignCertificatePeer = []struct {
+	name           string
+	keyType        constants.PrivateKeyType
+	expectKeyUsage x509.KeyUsage
+}{
+	{
+		name:           "peer with ecdsa key type",
+		keyType:        constants.PRIVATE_KEY_TYPE_ECDSA,
+		expectKeyUsage: x509.KeyUsageDigitalSignature,
+	},
+	{
+		name:           "peer with rsa key type",
+		keyType:        constants.PRIVATE_KEY_TYPE_RSA,
+		expectKeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	},
+}
+
+func TestSignCertificatePeer(t *testing.T) {
+	yamlPath := "./defaultCfg.yml"
+	var cfg model.CAConfig
+	if err := util.ReadYamlFileToStruct(yamlPath, &cfg); err != nil {
+		t.Fatalf("TestSignCertificatePeer: %v", err)
+	}
+
+	for _, testCase := range testCaseSignCertificatePeer {
+		t.Run(testCase.name, func(t *testing.T) {
+			for _, certType := range []constants.CertType{constants.CERT_TYPE_ROOT, constants.CERT_TYPE_INTERMEDIATE} {
+				if _, err := SignCertificate(certType, testCase.keyType, yamlPath, false); err != nil {
+					t.Fatalf("TestSignCertificatePeer (%s): %v", testCase.name, err)
+				}
+			}
+
+			cert, err := SignCertificate(constants.CERT_TYPE_PEER, testCase.keyType, yamlPath, false)
+			if err != nil {
+				t.Fatalf("TestSignCertificatePeer (%s): %v", testCase.name, err)
+			}
+			if cert.KeyUsage != testCase.expectKeyUsage {
+				t.Fatalf("TestSignCertificatePeer (%s): key usage %v != %v", testCase.name, cert.KeyUsage, testCase.expectKeyUsage)
+			}
+			if !reflect.DeepEqual(cert.ExtKeyUsage, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}) {
+				t.Fatalf("TestSignCertificatePeer (%s): unexpected extended key usage %v", testCase.name, cert.ExtKeyUsage)
+			}
+
+			for _, c := range []model.Certificate{cfg.CA.Root, cfg.CA.Intermediate, cfg.CA.Peer} {
+				for _, path := range []string{c.CertFilePath, c.CsrFilePath, c.KeyFilePath} {
+					if path == "" {
+						continue
+					}
+					if err := util.FileDelete(path); err != nil {
+						t.Fatalf("TestSignCertificatePeer (%s): %v", testCase.name, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// ed25519 keys are not generated by cert-go but can come with a third party public key
+const testKeyTypeEd25519 constants.PrivateKeyType = "ED25519"
+
+var testCaseIssueCertificateDefaultUsage = []struct {
+	name           string
+	certType       constants.CertType
+	keyType        constants.PrivateKeyType
+	expectKeyUsage x509.KeyUsage
+}{
+	{
+		name:           "server with ecdsa key",
+		certType:       constants.CERT_TYPE_SERVER,
+		keyType:        constants.PRIVATE_KEY_TYPE_ECDSA,
+		expectKeyUsage: x509.KeyUsageDigitalSignature,
+	},
+	{
+		name:           "server with rsa key",
+		certType:       constants.CERT_TYPE_SERVER,
+		keyType:        constants.PRIVATE_KEY_TYPE_RSA,
+		expectKeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	},
+	{
+		name:           "client with ed25519 key",
+		certType:       constants.CERT_TYPE_CLIENT,
+		keyType:        testKeyTypeEd25519,
+		expectKeyUsage: x509.KeyUsageDigitalSignature,
+	},
+	{
+		name:           "client with rsa key",
+		certType:       constants.CERT_TYPE_CLIENT,
+		keyType:        constants.PRIVATE_KEY_TYPE_RSA,
+		expectKeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	},
+	{
+		name:           "root with rsa key",
+		certType:       constants.CERT_TYPE_ROOT,
+		keyType:        constants.PRIVATE_KEY_TYPE_RSA,
+		expectKeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	},
+	{
+		name:           "custom type with rsa key",
+		certType:       "code-signing",
+		keyType:        constants.PRIVATE_KEY_TYPE_RSA,
+		expectKeyUsage: x509.KeyUsageDigitalSignature,
+	},
+}
+
+func TestIssueCertificateDefaultUsage(t *testing.T) {
+	for _, testCase := range testCaseIssueCertificateDefaultUsage {
+		t.Run(testCase.name, func(t *testing.T) {
+			var key crypto.Signer
+			switch testCase.keyType {
+			case testKeyTypeEd25519:
+				_, edKey, err := ed25519.GenerateKey(nil)
+				if err != nil {
+					t.Fatalf("TestIssueCertificateDefaultUsage (%s): %v", testCase.name, err)
+				}
+				key = edKey
+			default:
+				generated, err := GeneratePrivateKey(testCase.keyType)
+				if err != nil {
+					t.Fatalf("TestIssueCertificateDefaultUsage (%s): %v", testCase.name, err)
+				}
+				key = generated.(crypto.Signer)
+			}
+
+			cfg := model.Certificate{
+				Type:        string(testCase.certType),
+				CommonName:  testCase.name,
+				IsCA:        testCase.certType == constants.CERT_TYPE_ROOT,
+				ValidityDay: 1,
+			}
+			cert, err := IssueCertificate(cfg, key.Public(), nil, key)
+			if err != nil {
+				t.Fatalf("TestIssueCertificateDefaultUsage (%s): %v", testCase.name, err)
+			}
+			if cert.KeyUsage != testCase.expectKeyUsage {
+				t.Fatalf("TestIssueCertificateDefaultUsage (%s): key usage %v != %v", testCase.name, cert.KeyUsage, testCase.expectKeyUsage)
+			}
+			if cert.KeyUsage&x509.KeyUsageContentCommitment != 0 {
+				t.Fatalf("TestIssueCertificateDefaultUsage (%s): unexpected content commitment", testCase.name)
+			}
+		})
+	}
+}
+
+var testCaseValidityWindow = []struct {
+	name            string
+	cfg             model.Certificate
+	expectNotBefore time.Time
+	expectNotAfter  time.Time
+	errMsg          string
+}{
+	{
+		name:            "validity years",
+		cfg:             model.Certificate{ValidityYears: 1},
+		expectNotBefore: DeterministicEpoch,
+		expectNotAfter:  DeterministicEpoch.AddDate(1, 0, 0),
+	},
+	{
+		name:            "duration with backdate",
+		cfg:             model.Certificate{Validity: "2160h", Backdate: "5m"},
+		expectNotBefore: DeterministicEpoch.Add(-5 * time.Minute),
+		expectNotAfter:  DeterministicEpoch.Add(2160 * time.Hour),
+	},
+	{
+		name:            "not before with validity",
+		cfg:             model.Certificate{NotBefore: "2030-01-01", ValidityDay: 1, Validity: "1h"},
+		expectNotBefore: time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC),
+		expectNotAfter:  time.Date(2030, time.January, 2, 1, 0, 0, 0, time.UTC),
+	},
+	{
+		name:            "absolute window",
+		cfg:             model.Certificate{NotBefore: "2030-01-01T00:00:00Z", NotAfter: "2030-06-01T12:00:00+02:00"},
+		expectNotBefore: time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC),
+		expectNotAfter:  time.Date(2030, time.June, 1, 10, 0, 0, 0, time.UTC),
+	},
+	{
+		name:   "empty window",
+		cfg:    model.Certificate{},
+		errMsg: "not after 2024-01-01T00:00:00Z is not after not before 2024-01-01T00:00:00Z",
+	},
+	{
+		name:   "invalid duration",
+		cfg:    model.Certificate{Validity: "90d"},
+		errMsg: `validity: invalid duration: "90d", expected a duration like 2160h or 90m`,
+	},
+}
+
+func TestValidityWindow(t *testing.T) {
+	SetClock(FixedClock(DeterministicEpoch))
+	defer SetClock(nil)
+
+	for _, testCase := range testCaseValidityWindow {
+		t.Run(testCase.name, func(t *testing.T) {
+			notBefore, notAfter, err := validityWindow(testCase.cfg)
+			if testCase.errMsg != "" {
+				if err == nil || err.Error() != testCase.errMsg {
+					t.Fatalf("TestValidityWindow (%s): expected error %q but got %v", testCase.name, testCase.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TestValidityWindow (%s): %v", testCase.name, err)
+			}
+			if !notBefore.Equal(testCase.expectNotBefore) || !notAfter.Equal(testCase.expectNotAfter) {
+				t.Fatalf("TestValidityWindow (%s): actual %v - %v != expect %v - %v", testCase.name, notBefore, notAfter, testCase.expectNotBefore, testCase.expectNotAfter)
+			}
+		})
+	}
+}
+
+func TestIssueCertificateClampValidity(t *testing.T) {
+	SetClock(FixedClock(DeterministicEpoch))
+	defer SetClock(nil)
+
+	rootKey, err := GeneratePrivateKey(constants.PRIVATE_KEY_TYPE_ECDSA)
+	if err != nil {
+		t.Fatalf("TestIssueCertificateClampValidity: %v", err)
+	}
+	root, err := IssueCertificate(model.Certificate{Type: "root", IsCA: true, ValidityDay: 10}, rootKey.(crypto.Signer).Public(), nil, rootKey)
+	if err != nil {
+		t.Fatalf("TestIssueCertificateClampValidity: %v", err)
+	}
+
+	key, err := GeneratePrivateKey(constants.PRIVATE_KEY_TYPE_ECDSA)
+	if err != nil {
+		t.Fatalf("TestIssueCertificateClampValidity: %v", err)
+	}
+	cfg := model.Certificate{Type: "server", ValidityYears: 1}
+	cert, err := IssueCertificate(cfg, key.(crypto.Signer).Public(), root, rootKey)
+	if err != nil {
+		t.Fatalf("TestIssueCertificateClampValidity: %v", err)
+	}
+	if !cert.NotAfter.Equal(root.NotAfter) {
+		t.Fatalf("TestIssueCertificateClampValidity: not after %v is not clamped to %v", cert.NotAfter, root.NotAfter)
+	}
+
+	cfg.StrictValidity = true
+	if _, err := IssueCertificate(cfg, key.(crypto.Signer).Public(), root, rootKey); err == nil {
+		t.Fatalf("TestIssueCertificateClampValidity: expected error for strict validity")
+	}
+
+	// the root has expired, clamping would leave an empty validity
+	SetClock(FixedClock(DeterministicEpoch.AddDate(0, 0, 20)))
+	cfg.StrictValidity = false
+	if _, err := IssueCertificate(cfg, key.(crypto.Signer).Public(), root, rootKey); err == nil {
+		t.Fatalf("TestIssueCertificateClampValidity: expected error for an expired issuer")
+	}
+}
+
+func TestSignCertificateOverwriteKeepsPrevious(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "cfg.yml")
+	if _, err := WriteStarterConfig(yamlPath, StarterConfig{Organization: "overwrite", OutDir: "./ca", Leaves: []StarterLeaf{{Name: "web", Type: constants.CERT_TYPE_SERVER}}}, false); err != nil {
+		t.Fatalf("TestSignCertificateOverwriteKeepsPrevious: %v", err)
+	}
+	if _, err := InitPKI(yamlPath, ""); err != nil {
+		t.Fatalf("TestSignCertificateOverwriteKeepsPrevious: %v", err)
+	}
+	certPath := filepath.Join(dir, "ca", "web", "web.cert.pem")
+	previous, err := os.ReadFile(certPath)
+	if err != nil {
+		t.Fatalf("TestSignCertificateOverwriteKeepsPrevious: %v", err)
+	}
+
+	// signing fails without the key of the issuer, the previous certificate must be left in place
+	if err := util.FileDelete(filepath.Join(dir, "ca", "intermediate", "intermediate.key.pem")); err != nil {
+		t.Fatalf("TestSignCertificateOverwriteKeepsPrevious: %v", err)
+	}
+	if _, err := SignProfileCertificate("web", "", yamlPath, true); err == nil {
+		t.Fatalf("TestSignCertificateOverwriteKeepsPrevious: expected error without the issuer key")
+	}
+	current, err := os.ReadFile(certPath)
+	if err != nil {
+		t.Fatalf("TestSignCertificateOverwriteKeepsPrevious: previous certificate was removed: %v", err)
+	}
+	if !bytes.Equal(previous, current) {
+		t.Fatalf("TestSignCertificateOverwriteKeepsPrevious: previous certificate was changed")
+	}
+}
+
+func TestSignCertificateCAOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "cfg.yml")
+	if _, err := WriteStarterConfig(yamlPath, StarterConfig{Organization: "overwrite", OutDir: "./ca", Leaves: []StarterLeaf{{Name: "web", Type: constants.CERT_TYPE_SERVER}}}, false); err != nil {
+		t.Fatalf("TestSignCertificateCAOverwrite: %v", err)
+	}
+	if _, err := InitPKI(yamlPath, ""); err != nil {
+		t.Fatalf("TestSignCertificateCAOverwrite: %v", err)
+	}
+	rootCertPath := filepath.Join(dir, "ca", "root", "root.cert.pem")
+	previous, err := os.ReadFile(rootCertPath)
+	if err != nil {
+		t.Fatalf("TestSignCertificateCAOverwrite: %v", err)
+	}
+
+	// a leaf is overwritten with force alone, CA material needs a confirmation
+	if _, err := SignProfileCertificate("web", "", yamlPath, true); err != nil {
+		t.Fatalf("TestSignCertificateCAOverwrite: %v", err)
+	}
+	var asked []string
+	SetCAOverwriteConfirm(func(path string) bool {
+		asked = append(asked, path)
+		return false
+	})
+	defer SetCAOverwriteConfirm(nil)
+	for _, name := range []string{"root", "intermediate"} {
+		if _, err := SignProfileCertificate(name, "", yamlPath, true); err == nil {
+			t.Fatalf("TestSignCertificateCAOverwrite (%s): expected error without confirmation", name)
+		}
+	}
+	if len(asked) != 2 || asked[0] != rootCertPath {
+		t.Fatalf("TestSignCertificateCAOverwrite: unexpected confirmations %v", asked)
+	}
+	if current, err := os.ReadFile(rootCertPath); err != nil || !bytes.Equal(previous, current) {
+		t.Fatalf("TestSignCertificateCAOverwrite: root certificate was changed")
+	}
+
+	opts, err := RenewOptionsFromConfig(yamlPath, rootCertPath)
+	if err != nil {
+		t.Fatalf("TestSignCertificateCAOverwrite: %v", err)
+	}
+	opts.RotateKey = true
+	if _, _, err := RenewCertificate(rootCertPath, opts); err == nil {
+		t.Fatalf("TestSignCertificateCAOverwrite: expected error for rotating the root key without confirmation")
+	}
+
+	// a confirmed overwrite still never creates a new key for an existing root certificate
+	SetCAOverwriteConfirm(func(string) bool { return true })
+	if err := util.FileDelete(filepath.Join(dir, "ca", "root", "root.key.pem")); err != nil {
+		t.Fatalf("TestSignCertificateCAOverwrite: %v", err)
+	}
+	if _, err := SignProfileCertificate("root", "", yamlPath, true); err == nil {
+		t.Fatalf("TestSignCertificateCAOverwrite: expected error for a missing root key")
+	}
+	if util.FileExists(filepath.Join(dir, "ca", "root", "root.key.pem")) {
+		t.Fatalf("TestSignCertificateCAOverwrite: a new root key was created")
+	}
+}
+
+var testCaseIssueCertificateAltNames = []struct {
+	name   string
+	cfg    model.Certificate
+	errMsg string
+}{
+	{
+		name: "valid alt names",
+		cfg:  model.Certificate{IPAddresses: []string{"10.0.0.1", "::1"}, URIs: []string{"spiffe://example.com/ns/prod/sa/api"}},
+	},
+	{
+		name:   "invalid ip address",
+		cfg:    model.Certificate{IPAddresses: []string{"10.0.0.300"}},
+		errMsg: `invalid ip address "10.0.0.300"`,
+	},
+	{
+		name:   "uri without scheme",
+		cfg:    model.Certificate{URIs: []string{"example.com/ns/prod"}},
+		errMsg: `invalid uri "example.com/ns/prod", expected a scheme like spiffe://example.com/ns/prod`,
+	},
+}
+
+func TestIssueCertificateAltNames(t *testing.T) {
+	key, err := GeneratePrivateKey(constants.PRIVATE_KEY_TYPE_ECDSA)
+	if err != nil {
+		t.Fatalf("TestIssueCertificateAltNames: %v", err)
+	}
+	for _, testCase := range testCaseIssueCertificateAltNames {
+		t.Run(testCase.name, func(t *testing.T) {
+			cfg := testCase.cfg
+			cfg.Type, cfg.CommonName, cfg.ValidityDay = "server", "api", 1
+			cert, err := IssueCertificate(cfg, key.(crypto.Signer).Public(), nil, key)
+			if testCase.errMsg != "" {
+				if err == nil || err.Error() != testCase.errMsg {
+					t.Fatalf("TestIssueCertificateAltNames (%s): expected error %q but got %v", testCase.name, testCase.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TestIssueCertificateAltNames (%s): %v", testCase.name, err)
+			}
+			if len(cert.IPAddresses) != 2 || !cert.IPAddresses[0].Equal(net.ParseIP("10.0.0.1")) {
+				t.Fatalf("TestIssueCertificateAltNames (%s): unexpected ip addresses %v", testCase.name, cert.IPAddresses)
+			}
+			if len(cert.URIs) != 1 || cert.URIs[0].String() != "spiffe://example.com/ns/prod/sa/api" {
+				t.Fatalf("TestIssueCertificateAltNames (%s): unexpected uris %v", testCase.name, cert.URIs)
+			}
+		})
+	}
+}
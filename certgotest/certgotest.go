@@ -0,0 +1,293 @@
+// Package certgotest builds ephemeral PKIs for tests.
+//
+// New creates a root, intermediate, server and client certificate in memory
+// in one call, and returns ready to use tls.Config values for both sides:
+//
+//	pki := certgotest.New(t)
+//	srv := pki.NewTLSServer(t, handler)
+//	resp, err := pki.HTTPClient().Get(srv.URL)
+package certgotest
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	certgo "github.com/Alonza0314/cert-go"
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/internal/detrand"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+// Identity is a certificate with its private key.
+type Identity struct {
+	Certificate *x509.Certificate
+	PrivateKey  crypto.Signer
+	// Chain holds the issuing certificates up to, but excluding, the root.
+	Chain []*x509.Certificate
+}
+
+// TLSCertificate returns the identity as a tls.Certificate including its chain.
+func (i *Identity) TLSCertificate() tls.Certificate {
+	cert := tls.Certificate{
+		Certificate: [][]byte{i.Certificate.Raw},
+		PrivateKey:  i.PrivateKey,
+		Leaf:        i.Certificate,
+	}
+	for _, c := range i.Chain {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+	return cert
+}
+
+// CertPEM returns the PEM encoded certificate.
+func (i *Identity) CertPEM() []byte {
+	return util.EncodeCertificatePEM(i.Certificate)
+}
+
+// KeyPEM returns the PEM encoded private key in the format cert-go writes.
+func (i *Identity) KeyPEM() []byte {
+	keyPEM, err := util.EncodePrivateKeyPEM(i.PrivateKey)
+	if err != nil {
+		panic(err)
+	}
+	return keyPEM
+}
+
+// PKI is a root, intermediate, server and client hierarchy.
+type PKI struct {
+	Root         *Identity
+	Intermediate *Identity
+	Server       *Identity
+	Client       *Identity
+}
+
+type options struct {
+	keyType      constants.PrivateKeyType
+	organization string
+	dnsNames     []string
+	ipAddresses  []string
+	validityDay  int
+}
+
+// Option customizes the PKI built by New.
+type Option func(*options)
+
+// WithKeyType sets the key type of every certificate, the default is ECDSA.
+func WithKeyType(keyType constants.PrivateKeyType) Option {
+	return func(o *options) { o.keyType = keyType }
+}
+
+// WithOrganization sets the organization of every certificate.
+func WithOrganization(organization string) Option {
+	return func(o *options) { o.organization = organization }
+}
+
+// WithDNSNames sets the DNS names of the server certificate, the default is localhost.
+// The first name is the common name, without names it is the organization followed by server.
+func WithDNSNames(dnsNames ...string) Option {
+	return func(o *options) { o.dnsNames = dnsNames }
+}
+
+// WithIPAddresses sets the IP addresses of the server certificate, the default is 127.0.0.1 and ::1.
+func WithIPAddresses(ipAddresses ...string) Option {
+	return func(o *options) { o.ipAddresses = ipAddresses }
+}
+
+// WithValidityDays sets the validity of every certificate, the default is one day.
+func WithValidityDays(days int) Option {
+	return func(o *options) { o.validityDay = days }
+}
+
+// SetDeterministic makes the keys, serial numbers, signatures and validity windows of cert-go
+// reproducible for seed until the end of the test, the clock is fixed at certgo.DeterministicEpoch.
+// The keys are derived from seed with code that is not constant time, they are for tests only.
+// Tests using it must not run in parallel with other cert-go tests.
+func SetDeterministic(t testing.TB, seed int64) {
+	t.Helper()
+
+	restore := detrand.Enable(seed)
+	certgo.SetClock(certgo.FixedClock(certgo.DeterministicEpoch))
+	t.Cleanup(func() {
+		restore()
+		certgo.SetClock(nil)
+	})
+}
+
+// New builds a root → intermediate → server/client hierarchy in memory.
+// Any error fails the test.
+func New(t testing.TB, opts ...Option) *PKI {
+	t.Helper()
+
+	o := options{
+		keyType:      constants.PRIVATE_KEY_TYPE_ECDSA,
+		organization: "certgotest",
+		dnsNames:     []string{"localhost"},
+		ipAddresses:  []string{"127.0.0.1", "::1"},
+		validityDay:  1,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	certificate := func(certType constants.CertType) model.Certificate {
+		cfg := model.Certificate{
+			Type:         string(certType),
+			Organization: o.organization,
+			CommonName:   o.organization + " " + string(certType),
+			ValidityDay:  o.validityDay,
+		}
+		switch certType {
+		case constants.CERT_TYPE_ROOT, constants.CERT_TYPE_INTERMEDIATE:
+			cfg.IsCA = true
+			cfg.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+		case constants.CERT_TYPE_SERVER:
+			cfg.KeyUsage = x509.KeyUsageDigitalSignature
+			cfg.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+			if len(o.dnsNames) != 0 {
+				cfg.CommonName = o.dnsNames[0]
+			}
+			cfg.DNSNames = o.dnsNames
+			cfg.IPAddresses = o.ipAddresses
+		case constants.CERT_TYPE_CLIENT:
+			cfg.KeyUsage = x509.KeyUsageDigitalSignature
+			cfg.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		}
+		if o.keyType == constants.PRIVATE_KEY_TYPE_RSA && !cfg.IsCA {
+			cfg.KeyUsage |= x509.KeyUsageKeyEncipherment
+		}
+		return cfg
+	}
+
+	p := &PKI{}
+	p.Root = issue(t, certificate(constants.CERT_TYPE_ROOT), o.keyType, nil)
+	p.Intermediate = issue(t, certificate(constants.CERT_TYPE_INTERMEDIATE), o.keyType, p.Root)
+	p.Server = issue(t, certificate(constants.CERT_TYPE_SERVER), o.keyType, p.Intermediate)
+	p.Client = issue(t, certificate(constants.CERT_TYPE_CLIENT), o.keyType, p.Intermediate)
+	p.Server.Chain = []*x509.Certificate{p.Intermediate.Certificate}
+	p.Client.Chain = []*x509.Certificate{p.Intermediate.Certificate}
+	return p
+}
+
+func issue(t testing.TB, cfg model.Certificate, keyType constants.PrivateKeyType, parent *Identity) *Identity {
+	t.Helper()
+
+	key, err := certgo.GeneratePrivateKey(keyType)
+	if err != nil {
+		t.Fatalf("certgotest: %v", err)
+	}
+	signer := key.(crypto.Signer)
+
+	var parentCert *x509.Certificate
+	var parentKey crypto.Signer = signer
+	if parent != nil {
+		parentCert, parentKey = parent.Certificate, parent.PrivateKey
+	}
+	cert, err := certgo.IssueCertificate(cfg, signer.Public(), parentCert, parentKey)
+	if err != nil {
+		t.Fatalf("certgotest: %v", err)
+	}
+
+	return &Identity{
+		Certificate: cert,
+		PrivateKey:  signer,
+	}
+}
+
+// CertPool returns a pool trusting the root certificate.
+func (p *PKI) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(p.Root.Certificate)
+	return pool
+}
+
+// ServerTLSConfig returns a server config presenting the server certificate
+// and requiring client certificates issued by the PKI.
+func (p *PKI) ServerTLSConfig() *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{p.Server.TLSCertificate()},
+		ClientCAs:    p.CertPool(),
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// ClientTLSConfig returns a client config trusting the root certificate
+// and presenting the client certificate.
+func (p *PKI) ClientTLSConfig() *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{p.Client.TLSCertificate()},
+		RootCAs:      p.CertPool(),
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// NewTLSServer starts an httptest.Server using ServerTLSConfig,
+// the server is closed when the test finishes.
+func (p *PKI) NewTLSServer(t testing.TB, handler http.Handler) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(handler)
+	srv.TLS = p.ServerTLSConfig()
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// HTTPClient returns an http.Client using ClientTLSConfig.
+func (p *PKI) HTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: p.ClientTLSConfig(),
+		},
+	}
+}
+
+// Files holds the paths of a PKI written in cert-go's file layout.
+type Files struct {
+	Dir          string
+	Root         model.Certificate
+	Intermediate model.Certificate
+	Server       model.Certificate
+	Client       model.Certificate
+}
+
+// WriteFiles writes every certificate and private key to a temporary directory
+// removed when the test finishes.
+func (p *PKI) WriteFiles(t testing.TB) *Files {
+	t.Helper()
+
+	dir := t.TempDir()
+	files := &Files{Dir: dir}
+	write := func(name string, id *Identity) model.Certificate {
+		cfg := model.Certificate{
+			Type:         name,
+			CertFilePath: filepath.Join(dir, name, name+".cert.pem"),
+			KeyFilePath:  filepath.Join(dir, name, name+".key.pem"),
+		}
+		if err := util.FileDirCreate(cfg.CertFilePath); err != nil {
+			t.Fatalf("certgotest: %v", err)
+		}
+		if err := util.FileWrite(cfg.CertFilePath, id.CertPEM(), 0644); err != nil {
+			t.Fatalf("certgotest: %v", err)
+		}
+		if err := util.FileWrite(cfg.KeyFilePath, id.KeyPEM(), 0600); err != nil {
+			t.Fatalf("certgotest: %v", err)
+		}
+		return cfg
+	}
+
+	files.Root = write(string(constants.CERT_TYPE_ROOT), p.Root)
+	files.Intermediate = write(string(constants.CERT_TYPE_INTERMEDIATE), p.Intermediate)
+	files.Server = write(string(constants.CERT_TYPE_SERVER), p.Server)
+	files.Client = write(string(constants.CERT_TYPE_CLIENT), p.Client)
+	files.Intermediate.ParentCertPath, files.Intermediate.ParentKeyPath = files.Root.CertFilePath, files.Root.KeyFilePath
+	files.Server.ParentCertPath, files.Server.ParentKeyPath = files.Intermediate.CertFilePath, files.Intermediate.KeyFilePath
+	files.Client.ParentCertPath, files.Client.ParentKeyPath = files.Intermediate.CertFilePath, files.Intermediate.KeyFilePath
+	return files
+}
@@ -0,0 +1,127 @@
+package certgotest
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"testing"
+
+	certgo "github.com/Alonza0314/cert-go"
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+var testCaseNew = []struct {
+	name    string
+	keyType constants.PrivateKeyType
+}{
+	{
+		name:    "ecdsa",
+		keyType: constants.PRIVATE_KEY_TYPE_ECDSA,
+	},
+	{
+		name:    "rsa",
+		keyType: constants.PRIVATE_KEY_TYPE_RSA,
+	},
+}
+
+func TestNew(t *testing.T) {
+	for _, testCase := range testCaseNew {
+		t.Run(testCase.name, func(t *testing.T) {
+			pki := New(t, WithKeyType(testCase.keyType))
+
+			srv := pki.NewTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+			}))
+
+			resp, err := pki.HTTPClient().Get(srv.URL)
+			if err != nil {
+				t.Fatalf("TestNew (%s): %v", testCase.name, err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("TestNew (%s): %v", testCase.name, err)
+			}
+			if string(body) != pki.Client.Certificate.Subject.CommonName {
+				t.Fatalf("TestNew (%s): server saw client %q", testCase.name, body)
+			}
+
+			// a client without certificate must be rejected
+			noCert := pki.ClientTLSConfig()
+			noCert.Certificates = nil
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: noCert}}
+			if resp, err := client.Get(srv.URL); err == nil {
+				resp.Body.Close()
+				t.Fatalf("TestNew (%s): client without certificate should be rejected", testCase.name)
+			}
+
+			// a client trusting another root must reject the server
+			other := New(t)
+			conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), other.ClientTLSConfig())
+			if err == nil {
+				conn.Close()
+				t.Fatalf("TestNew (%s): server certificate should not be trusted by another PKI", testCase.name)
+			}
+		})
+	}
+}
+
+func newDeterministic(t *testing.T, seed int64) *PKI {
+	var pki *PKI
+	t.Run("seed", func(t *testing.T) {
+		SetDeterministic(t, seed)
+		pki = New(t)
+	})
+	return pki
+}
+
+func TestSetDeterministic(t *testing.T) {
+	first := newDeterministic(t, 1)
+	second := newDeterministic(t, 1)
+	other := newDeterministic(t, 2)
+	if !bytes.Equal(first.Server.Certificate.Raw, second.Server.Certificate.Raw) || !bytes.Equal(first.Server.KeyPEM(), second.Server.KeyPEM()) {
+		t.Fatalf("TestSetDeterministic: same seed should give the same certificates and keys")
+	}
+	if bytes.Equal(first.Server.Certificate.Raw, other.Server.Certificate.Raw) {
+		t.Fatalf("TestSetDeterministic: different seeds should give different certificates")
+	}
+	if !first.Root.Certificate.NotBefore.Equal(certgo.DeterministicEpoch) {
+		t.Fatalf("TestSetDeterministic: not before %s != %s", first.Root.Certificate.NotBefore, certgo.DeterministicEpoch)
+	}
+
+	// the defaults are restored at the end of the test
+	if fresh := New(t); fresh.Root.Certificate.NotBefore.Equal(certgo.DeterministicEpoch) {
+		t.Fatalf("TestSetDeterministic: clock should be restored")
+	}
+}
+
+func TestNewWithoutDNSNames(t *testing.T) {
+	pki := New(t, WithDNSNames())
+	if len(pki.Server.Certificate.DNSNames) != 0 {
+		t.Fatalf("TestNewWithoutDNSNames: unexpected dns names %v", pki.Server.Certificate.DNSNames)
+	}
+	if cn := pki.Server.Certificate.Subject.CommonName; cn != "certgotest server" {
+		t.Fatalf("TestNewWithoutDNSNames: common name %q != %q", cn, "certgotest server")
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	pki := New(t)
+	files := pki.WriteFiles(t)
+
+	cert, err := util.ReadCertificate(files.Server.CertFilePath)
+	if err != nil {
+		t.Fatalf("TestWriteFiles: %v", err)
+	}
+	if !cert.Equal(pki.Server.Certificate) {
+		t.Fatalf("TestWriteFiles: server certificate is not equal")
+	}
+	if _, err := util.ReadPrivateKey(files.Server.KeyFilePath); err != nil {
+		t.Fatalf("TestWriteFiles: %v", err)
+	}
+	if files.Server.ParentCertPath != files.Intermediate.CertFilePath {
+		t.Fatalf("TestWriteFiles: server parent should be the intermediate certificate")
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	certgo "github.com/Alonza0314/cert-go"
+	"github.com/Alonza0314/cert-go/util"
+	"github.com/spf13/cobra"
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "used to renew the certificates of the configuration in the background",
+	Long:  "used to check the certificates of the configuration periodically, renew those inside the renewal window and run the hooks of the agent section, the status is served as json on the health address",
+	Run:   agent,
+}
+
+func init() {
+	agentCmd.Flags().StringP("config", "c", "", "specify the configuration yaml file path")
+	agentCmd.Flags().String("health-addr", "", "specify the loopback address of the health endpoint, overrides the health_addr of the configuration")
+	agentCmd.Flags().Bool("once", false, "check the certificates once and exit, with status 1 if a renewal or a hook failed")
+
+	cobra.CheckErr(agentCmd.MarkFlagRequired("config"))
+
+	rootCmd.AddCommand(agentCmd)
+}
+
+func agent(cmd *cobra.Command, args []string) {
+	yamlPath, err := cmd.Flags().GetString("config")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		os.Exit(1)
+	}
+	healthAddr, err := cmd.Flags().GetString("health-addr")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		os.Exit(1)
+	}
+	once, err := cmd.Flags().GetBool("once")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		os.Exit(1)
+	}
+
+	a, err := certgo.NewAgent(yamlPath)
+	if err != nil {
+		util.Logger().Error("failed to start agent", "error", err)
+		os.Exit(1)
+	}
+	if healthAddr != "" {
+		if err := a.SetHealthAddr(healthAddr); err != nil {
+			util.Logger().Error(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if once {
+		if status := a.Check(ctx); !status.Healthy {
+			os.Exit(1)
+		}
+		return
+	}
+	if err := a.Run(ctx); err != nil {
+		util.Logger().Error("agent failed", "error", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,13 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "used to manage the audit log",
+	Long:  "used to manage the audit log, the hash-chained record of the keys, csrs and certificates written with --audit-log",
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	certgo "github.com/Alonza0314/cert-go"
+	"github.com/Alonza0314/cert-go/util"
+	"github.com/spf13/cobra"
+)
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "used to verify the hash chain of the audit log",
+	Long:  "used to verify the hash chain of the audit log given by --audit-log, the command exits with status 1 if an entry was changed, inserted or removed",
+	Run:   auditVerify,
+}
+
+func init() {
+	auditCmd.AddCommand(auditVerifyCmd)
+}
+
+func auditVerify(cmd *cobra.Command, args []string) {
+	path, err := cmd.Flags().GetString("audit-log")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	if path == "" {
+		util.Logger().Error("audit log path is required, use --audit-log")
+		os.Exit(1)
+	}
+
+	result, err := certgo.VerifyAuditLog(path)
+	if err != nil {
+		util.Logger().Error("audit log is invalid", "path", path, "error", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%d entries, last hash %s\n", result.Entries, result.LastHash)
+	util.Logger().Info("audit log is valid", "path", path)
+}
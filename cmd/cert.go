@@ -5,7 +5,7 @@ import (
 
 	certgo "github.com/Alonza0314/cert-go"
 	"github.com/Alonza0314/cert-go/constants"
-	logger "github.com/Alonza0314/logger-go"
+	"github.com/Alonza0314/cert-go/util"
 	"github.com/spf13/cobra"
 )
 
@@ -18,19 +18,14 @@ var certCmd = &cobra.Command{
 
 func init() {
 	certCmd.Flags().StringP("yaml", "y", "", "specify the configuration yaml file path")
-	certCmd.Flags().StringP("type", "t", "", "specify the type of the certificate: [root, intermediate, server, client]")
+	certCmd.Flags().StringP("type", "t", "", "specify the type of the certificate: [root, intermediate, server, client, peer]")
 	certCmd.Flags().BoolP("force", "f", false, "overwrite the certificate if it already exists")
-	certCmd.Flags().StringP("key", "k", "", "specify the type of the private key, <ecdsa> or <rsa>")
+	certCmd.Flags().StringP("key", "k", "", "specify the type of the private key, <ecdsa> or <rsa>, overrides the key_type of the configuration")
+	certCmd.Flags().StringP("profile", "p", "", "specify the name of the certificate profile in the configuration yaml file")
 
-	if err := certCmd.MarkFlagRequired("yaml"); err != nil {
-		logger.Error("cert-go", err.Error())
-	}
-	if err := certCmd.MarkFlagRequired("type"); err != nil {
-		logger.Error("cert-go", err.Error())
-	}
-	if err := certCmd.MarkFlagRequired("key"); err != nil {
-		logger.Error("cert-go", err.Error())
-	}
+	cobra.CheckErr(certCmd.MarkFlagRequired("yaml"))
+	certCmd.MarkFlagsOneRequired("type", "profile")
+	certCmd.MarkFlagsMutuallyExclusive("type", "profile")
 
 	createCmd.AddCommand(certCmd)
 }
@@ -38,38 +33,61 @@ func init() {
 func createCert(cmd *cobra.Command, args []string) {
 	yamlPath, err := cmd.Flags().GetString("yaml")
 	if err != nil {
-		logger.Error("cert-go", err.Error())
+		util.Logger().Error(err.Error())
 		return
 	}
 	certType, err := cmd.Flags().GetString("type")
 	if err != nil {
-		logger.Error("cert-go", err.Error())
+		util.Logger().Error(err.Error())
 		return
 	}
 	force, err := cmd.Flags().GetBool("force")
 	if err != nil {
-		logger.Error("cert-go", err.Error())
+		util.Logger().Error(err.Error())
 		return
 	}
 	keyType, err := cmd.Flags().GetString("key")
 	if err != nil {
-		logger.Error("cert-go", err.Error())
+		util.Logger().Error(err.Error())
+		return
+	}
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		util.Logger().Error(err.Error())
 		return
 	}
 
+	// an empty key type keeps the key_type of the configuration
 	var privateKeyType constants.PrivateKeyType
-	if keyType == "rsa" {
-		privateKeyType = constants.PRIVATE_KEY_TYPE_RSA
-	} else {
-		privateKeyType = constants.PRIVATE_KEY_TYPE_ECDSA
+	if keyType != "" {
+		if privateKeyType, err = util.ParsePrivateKeyType(keyType); err != nil {
+			util.Logger().Error(err.Error())
+			return
+		}
+	}
+
+	if profile != "" {
+		util.Logger().Info("start to create cert", "profile", profile, "yaml", yamlPath)
+		if _, err := certgo.SignProfileCertificate(profile, privateKeyType, yamlPath, force); err != nil {
+			if strings.Contains(err.Error(), "already exists") {
+				util.Logger().Error("use --force(f) to overwrite the cert")
+			}
+			if strings.Contains(err.Error(), "CA material") {
+				util.Logger().Error("use --force-ca to overwrite the CA cert")
+			}
+			util.Logger().Error("failed to create cert", "error", err)
+			return
+		}
+		util.Logger().Info("create cert success", "profile", profile)
+		return
 	}
 
-	if certType != string(constants.CERT_TYPE_ROOT) && certType != string(constants.CERT_TYPE_INTERMEDIATE) && certType != string(constants.CERT_TYPE_SERVER) && certType != string(constants.CERT_TYPE_CLIENT) {
-		logger.Error("cert-go", "invalid cert type, please specify the type of the certificate: [root, intermediate, server, client]")
+	if certType != string(constants.CERT_TYPE_ROOT) && certType != string(constants.CERT_TYPE_INTERMEDIATE) && certType != string(constants.CERT_TYPE_SERVER) && certType != string(constants.CERT_TYPE_CLIENT) && certType != string(constants.CERT_TYPE_PEER) {
+		util.Logger().Error("invalid cert type, please specify the type of the certificate: [root, intermediate, server, client, peer]")
 		return
 	}
 
-	logger.Info("cert-go", "start to create cert")
+	util.Logger().Info("start to create cert", "type", certType, "yaml", yamlPath)
 	switch constants.CertType(certType) {
 	case constants.CERT_TYPE_ROOT:
 		_, err = certgo.SignCertificate(constants.CERT_TYPE_ROOT, privateKeyType, yamlPath, force)
@@ -79,13 +97,18 @@ func createCert(cmd *cobra.Command, args []string) {
 		_, err = certgo.SignCertificate(constants.CERT_TYPE_SERVER, privateKeyType, yamlPath, force)
 	case constants.CERT_TYPE_CLIENT:
 		_, err = certgo.SignCertificate(constants.CERT_TYPE_CLIENT, privateKeyType, yamlPath, force)
+	case constants.CERT_TYPE_PEER:
+		_, err = certgo.SignCertificate(constants.CERT_TYPE_PEER, privateKeyType, yamlPath, force)
 	}
 	if err != nil {
 		if strings.Contains(err.Error(), "already exists") {
-			logger.Error("cert-go", "use --force(f) to overwrite the cert")
+			util.Logger().Error("use --force(f) to overwrite the cert")
+		}
+		if strings.Contains(err.Error(), "CA material") {
+			util.Logger().Error("use --force-ca to overwrite the CA cert")
 		}
-		logger.Error("cert-go", "failed to create cert")
+		util.Logger().Error("failed to create cert", "error", err)
 		return
 	}
-	logger.Info("cert-go", "create cert success")
+	util.Logger().Info("create cert success", "type", certType)
 }
@@ -0,0 +1,13 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "used to manage the configuration yaml file",
+	Long:  "used to manage the configuration yaml file",
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	certgo "github.com/Alonza0314/cert-go"
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/util"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "used to write a starter configuration yaml file",
+	Long:  "used to write a starter configuration yaml file with a root, an intermediate and leaf profiles, values not given by flags are prompted when stdin is a terminal",
+	Run:   configInit,
+}
+
+func init() {
+	configInitCmd.Flags().StringP("out", "o", "cfg.yml", "specify the output path of the configuration yaml file")
+	configInitCmd.Flags().String("org", "", "specify the organization of the certificates")
+	configInitCmd.Flags().StringSlice("dns", []string{"localhost"}, "specify the dns names of the server and peer certificates")
+	configInitCmd.Flags().StringSlice("ip", []string{"127.0.0.1"}, "specify the ip addresses of the server and peer certificates")
+	configInitCmd.Flags().StringP("key", "k", "ecdsa", "specify the key_type of the certificates, <ecdsa> or <rsa>")
+	configInitCmd.Flags().String("out-dir", "./ca", "specify the directory the certificates are written to, relative to the configuration yaml file")
+	configInitCmd.Flags().StringSlice("server", nil, "specify the names of the server profiles")
+	configInitCmd.Flags().StringSlice("client", nil, "specify the names of the client profiles")
+	configInitCmd.Flags().StringSlice("peer", nil, "specify the names of the peer profiles")
+	configInitCmd.Flags().BoolP("force", "f", false, "overwrite the configuration yaml file if it already exists")
+	configInitCmd.Flags().Bool("no-prompt", false, "never prompt, use the flags and their defaults")
+
+	configCmd.AddCommand(configInitCmd)
+}
+
+func configInit(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	out, err := flags.GetString("out")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	force, err := flags.GetBool("force")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	noPrompt, err := flags.GetBool("no-prompt")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+
+	var starter certgo.StarterConfig
+	if starter.Organization, err = flags.GetString("org"); err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	if starter.DNSNames, err = flags.GetStringSlice("dns"); err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	if starter.IPAddresses, err = flags.GetStringSlice("ip"); err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	if starter.KeyType, err = flags.GetString("key"); err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	if starter.OutDir, err = flags.GetString("out-dir"); err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	leaves := make(map[constants.CertType][]string)
+	for _, certType := range []constants.CertType{constants.CERT_TYPE_SERVER, constants.CERT_TYPE_CLIENT, constants.CERT_TYPE_PEER} {
+		if leaves[certType], err = flags.GetStringSlice(string(certType)); err != nil {
+			util.Logger().Error(err.Error())
+			return
+		}
+	}
+	leavesChanged := flags.Changed("server") || flags.Changed("client") || flags.Changed("peer")
+
+	if !noPrompt && isTerminal(os.Stdin) {
+		p := prompter{reader: bufio.NewReader(os.Stdin)}
+		if !flags.Changed("org") {
+			starter.Organization = p.ask("Organization", starter.Organization)
+		}
+		if !flags.Changed("dns") {
+			starter.DNSNames = p.askList("DNS names", starter.DNSNames)
+		}
+		if !flags.Changed("ip") {
+			starter.IPAddresses = p.askList("IP addresses", starter.IPAddresses)
+		}
+		if !flags.Changed("key") {
+			starter.KeyType = p.ask("Key type (ecdsa or rsa)", starter.KeyType)
+		}
+		if !flags.Changed("out-dir") {
+			starter.OutDir = p.ask("Output directory", starter.OutDir)
+		}
+		if !leavesChanged {
+			leaves[constants.CERT_TYPE_SERVER] = p.askList("Server profiles", []string{"server"})
+			leaves[constants.CERT_TYPE_CLIENT] = p.askList("Client profiles", []string{"client"})
+			leaves[constants.CERT_TYPE_PEER] = p.askList("Peer profiles", nil)
+			leavesChanged = true
+		}
+		if p.err != nil {
+			util.Logger().Error("failed to read answer", "error", p.err)
+			return
+		}
+	}
+	if !leavesChanged {
+		leaves[constants.CERT_TYPE_SERVER] = []string{"server"}
+		leaves[constants.CERT_TYPE_CLIENT] = []string{"client"}
+	}
+	for _, certType := range []constants.CertType{constants.CERT_TYPE_SERVER, constants.CERT_TYPE_CLIENT, constants.CERT_TYPE_PEER} {
+		for _, name := range leaves[certType] {
+			starter.Leaves = append(starter.Leaves, certgo.StarterLeaf{Name: name, Type: certType})
+		}
+	}
+
+	util.Logger().Info("start to write config", "path", out)
+	if _, err := certgo.WriteStarterConfig(out, starter, force); err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			util.Logger().Error("use --force(f) to overwrite the config")
+		}
+		util.Logger().Error("failed to write config", "error", err)
+		return
+	}
+	util.Logger().Info("write config success", "path", out)
+}
+
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// prompter asks questions on stdout and reads the answers line by line, an empty answer keeps the default.
+type prompter struct {
+	reader *bufio.Reader
+	err    error
+}
+
+func (p *prompter) ask(question, def string) string {
+	if p.err != nil {
+		return def
+	}
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+	line, err := p.reader.ReadString('\n')
+	if err != nil && line == "" {
+		p.err = err
+		return def
+	}
+	if line = strings.TrimSpace(line); line == "" {
+		return def
+	}
+	return line
+}
+
+func (p *prompter) askList(question string, def []string) []string {
+	answer := p.ask(question+" (comma separated, - for none)", strings.Join(def, ","))
+	if answer == "-" {
+		return nil
+	}
+	var list []string
+	for _, item := range strings.Split(answer, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
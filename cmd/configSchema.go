@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"os"
+
+	certgo "github.com/Alonza0314/cert-go"
+	"github.com/Alonza0314/cert-go/util"
+	"github.com/spf13/cobra"
+)
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "used to print the json schema of the configuration file",
+	Long:  "used to print the json schema of the configuration file, it applies to the yaml, json and toml formats",
+	Run:   configSchema,
+}
+
+func init() {
+	configSchemaCmd.Flags().StringP("out", "o", "", "specify the output path of the schema, stdout by default")
+
+	configCmd.AddCommand(configSchemaCmd)
+}
+
+func configSchema(cmd *cobra.Command, args []string) {
+	out, err := cmd.Flags().GetString("out")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+
+	schema, err := certgo.ConfigSchema()
+	if err != nil {
+		util.Logger().Error("failed to generate schema", "error", err)
+		return
+	}
+	if out == "" {
+		if _, err := os.Stdout.Write(schema); err != nil {
+			util.Logger().Error("failed to write schema", "error", err)
+		}
+		return
+	}
+	if err := util.FileWrite(out, schema, 0644); err != nil {
+		util.Logger().Error("failed to write schema", "error", err)
+		return
+	}
+	util.Logger().Info("write schema success", "path", out)
+}
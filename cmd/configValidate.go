@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	certgo "github.com/Alonza0314/cert-go"
+	"github.com/Alonza0314/cert-go/util"
+	"github.com/spf13/cobra"
+)
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "used to validate the configuration yaml file",
+	Long:  "used to validate the configuration yaml file, every problem is printed with its line and the command exits with status 1 if there is any",
+	Run:   configValidate,
+}
+
+func init() {
+	configValidateCmd.Flags().StringP("yaml", "y", "", "specify the configuration yaml file path")
+
+	cobra.CheckErr(configValidateCmd.MarkFlagRequired("yaml"))
+
+	configCmd.AddCommand(configValidateCmd)
+}
+
+func configValidate(cmd *cobra.Command, args []string) {
+	yamlPath, err := cmd.Flags().GetString("yaml")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+
+	problems, err := certgo.ValidateConfigFile(yamlPath)
+	if err != nil {
+		util.Logger().Error("failed to validate config", "error", err)
+		os.Exit(1)
+	}
+	for _, problem := range problems {
+		location := yamlPath
+		if problem.Line > 0 {
+			location = fmt.Sprintf("%s:%d", yamlPath, problem.Line)
+		}
+		if problem.Profile != "" {
+			fmt.Printf("%s: %s: %s\n", location, problem.Profile, problem.Message)
+		} else {
+			fmt.Printf("%s: %s\n", location, problem.Message)
+		}
+	}
+	if len(problems) != 0 {
+		util.Logger().Error("config is invalid", "problems", len(problems))
+		os.Exit(1)
+	}
+	util.Logger().Info("config is valid", "yaml", yamlPath)
+}
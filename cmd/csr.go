@@ -7,7 +7,6 @@ import (
 	"github.com/Alonza0314/cert-go/constants"
 	"github.com/Alonza0314/cert-go/model"
 	"github.com/Alonza0314/cert-go/util"
-	logger "github.com/Alonza0314/logger-go"
 	"github.com/spf13/cobra"
 )
 
@@ -20,19 +19,14 @@ var csrCmd = &cobra.Command{
 
 func init() {
 	csrCmd.Flags().StringP("yaml", "y", "", "specify the configuration yaml file path")
-	csrCmd.Flags().StringP("type", "t", "", "specify the type of the certificate: [intermediate, server, client]")
+	csrCmd.Flags().StringP("type", "t", "", "specify the type of the certificate: [intermediate, server, client, peer]")
 	csrCmd.Flags().BoolP("force", "f", false, "overwrite the csr if it already exists")
-	csrCmd.Flags().StringP("key", "k", "", "specify the type of the private key, <ecdsa> or <rsa>")
+	csrCmd.Flags().StringP("key", "k", "", "specify the type of the private key, <ecdsa> or <rsa>, overrides the key_type of the configuration")
+	csrCmd.Flags().StringP("profile", "p", "", "specify the name of the certificate profile in the configuration yaml file")
 
-	if err := csrCmd.MarkFlagRequired("yaml"); err != nil {
-		logger.Error("cert-go", err.Error())
-	}
-	if err := csrCmd.MarkFlagRequired("type"); err != nil {
-		logger.Error("cert-go", err.Error())
-	}
-	if err := csrCmd.MarkFlagRequired("key"); err != nil {
-		logger.Error("cert-go", err.Error())
-	}
+	cobra.CheckErr(csrCmd.MarkFlagRequired("yaml"))
+	csrCmd.MarkFlagsOneRequired("type", "profile")
+	csrCmd.MarkFlagsMutuallyExclusive("type", "profile")
 
 	createCmd.AddCommand(csrCmd)
 }
@@ -40,57 +34,74 @@ func init() {
 func createCsr(cmd *cobra.Command, args []string) {
 	yamlPath, err := cmd.Flags().GetString("yaml")
 	if err != nil {
-		logger.Error("cert-go", err.Error())
+		util.Logger().Error(err.Error())
 		return
 	}
 	csrType, err := cmd.Flags().GetString("type")
 	if err != nil {
-		logger.Error("cert-go", err.Error())
+		util.Logger().Error(err.Error())
 		return
 	}
 	force, err := cmd.Flags().GetBool("force")
 	if err != nil {
-		logger.Error("cert-go", err.Error())
+		util.Logger().Error(err.Error())
 		return
 	}
 	keyType, err := cmd.Flags().GetString("key")
 	if err != nil {
-		logger.Error("cert-go", err.Error())
+		util.Logger().Error(err.Error())
+		return
+	}
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		util.Logger().Error(err.Error())
 		return
 	}
 
+	// an empty key type keeps the key_type of the configuration
 	var privateKeyType constants.PrivateKeyType
-	if keyType == "rsa" {
-		privateKeyType = constants.PRIVATE_KEY_TYPE_RSA
-	} else {
-		privateKeyType = constants.PRIVATE_KEY_TYPE_ECDSA
+	if keyType != "" {
+		if privateKeyType, err = util.ParsePrivateKeyType(keyType); err != nil {
+			util.Logger().Error(err.Error())
+			return
+		}
 	}
 
-	if csrType != string(constants.CERT_TYPE_INTERMEDIATE) && csrType != string(constants.CERT_TYPE_SERVER) && csrType != string(constants.CERT_TYPE_CLIENT) {
-		logger.Error("cert-go", "invalid csr type, please specify the type of the certificate: [intermediate, server, client]")
+	if profile == "" && csrType != string(constants.CERT_TYPE_INTERMEDIATE) && csrType != string(constants.CERT_TYPE_SERVER) && csrType != string(constants.CERT_TYPE_CLIENT) && csrType != string(constants.CERT_TYPE_PEER) {
+		util.Logger().Error("invalid csr type, please specify the type of the certificate: [intermediate, server, client, peer]")
 		return
 	}
 
-	logger.Info("cert-go", "start to create csr")
-	var cfg model.CAConfig
-	if err := util.ReadYamlFileToStruct(yamlPath, &cfg); err != nil {
-		logger.Error("cert-go", "failed to create csr")
+	util.Logger().Info("start to create csr", "type", csrType, "profile", profile, "yaml", yamlPath)
+	cfg, err := certgo.ReadConfig(yamlPath)
+	if err != nil {
+		util.Logger().Error("failed to create csr", "error", err)
 		return
 	}
-	switch constants.CertType(csrType) {
-	case constants.CERT_TYPE_INTERMEDIATE:
-		_, err = certgo.CreateCsr(cfg.CA.Intermediate, privateKeyType, force)
-	case constants.CERT_TYPE_SERVER:
-		_, err = certgo.CreateCsr(cfg.CA.Server, privateKeyType, force)
-	case constants.CERT_TYPE_CLIENT:
-		_, err = certgo.CreateCsr(cfg.CA.Client, privateKeyType, force)
+	if profile != "" {
+		var cert model.Certificate
+		cert, err = certgo.ResolveProfile(cfg.CA, profile)
+		if err == nil {
+			_, err = certgo.CreateCsr(cert, privateKeyType, force)
+		}
+	} else {
+		switch constants.CertType(csrType) {
+		case constants.CERT_TYPE_INTERMEDIATE:
+			_, err = certgo.CreateCsr(cfg.CA.Intermediate, privateKeyType, force)
+		case constants.CERT_TYPE_SERVER:
+			_, err = certgo.CreateCsr(cfg.CA.Server, privateKeyType, force)
+		case constants.CERT_TYPE_CLIENT:
+			_, err = certgo.CreateCsr(cfg.CA.Client, privateKeyType, force)
+		case constants.CERT_TYPE_PEER:
+			_, err = certgo.CreateCsr(cfg.CA.Peer, privateKeyType, force)
+		}
 	}
 	if err != nil {
 		if strings.Contains(err.Error(), "already exists") {
-			logger.Error("cert-go", "use --force(f) to overwrite the csr")
+			util.Logger().Error("use --force(f) to overwrite the csr")
 		}
-		logger.Error("cert-go", "failed to create csr")
+		util.Logger().Error("failed to create csr", "error", err)
 		return
 	}
-	logger.Info("cert-go", "create csr success")
+	util.Logger().Info("create csr success", "type", csrType, "profile", profile)
 }
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+
+	certgo "github.com/Alonza0314/cert-go"
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/util"
+	"github.com/spf13/cobra"
+)
+
+var expiryCmd = &cobra.Command{
+	Use:   "expiry",
+	Short: "used to report the expiry of the certificates in directories",
+	Long:  "used to report the subject, issuer, serial, path and days remaining of every certificate in the directories, the command exits with status 1 if any certificate expires within the threshold",
+	Run:   expiry,
+}
+
+func init() {
+	expiryCmd.Flags().StringSliceP("dir", "d", nil, "specify the directories to walk, can be repeated")
+	expiryCmd.Flags().String("within", "30d", "specify the threshold, a number of days like 30d or a duration like 720h")
+	expiryCmd.Flags().StringP("report", "r", string(constants.REPORT_FORMAT_TABLE), "specify the report format, <table>, <json> or <prometheus>")
+	expiryCmd.Flags().StringP("out", "o", "", "specify the output path of the report, e.g. a .prom file of the textfile collector, stdout by default")
+
+	cobra.CheckErr(expiryCmd.MarkFlagRequired("dir"))
+
+	rootCmd.AddCommand(expiryCmd)
+}
+
+func expiry(cmd *cobra.Command, args []string) {
+	dirs, err := cmd.Flags().GetStringSlice("dir")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		os.Exit(1)
+	}
+	within, err := cmd.Flags().GetString("within")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		os.Exit(1)
+	}
+	report, err := cmd.Flags().GetString("report")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		os.Exit(1)
+	}
+	out, err := cmd.Flags().GetString("out")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		os.Exit(1)
+	}
+
+	threshold, err := util.ParseDayDuration(within)
+	if err != nil {
+		util.Logger().Error(err.Error())
+		os.Exit(1)
+	}
+	format := constants.ReportFormat(report)
+	switch format {
+	case constants.REPORT_FORMAT_TABLE, constants.REPORT_FORMAT_JSON, constants.REPORT_FORMAT_PROMETHEUS:
+	default:
+		util.Logger().Error("unknown report format, expected table, json or prometheus", "report", report)
+		os.Exit(1)
+	}
+
+	items, err := certgo.ScanExpiry(dirs, threshold)
+	if err != nil {
+		util.Logger().Error("failed to scan expiry", "error", err)
+		os.Exit(1)
+	}
+	var buf bytes.Buffer
+	if err := certgo.WriteExpiryReport(&buf, items, format); err != nil {
+		util.Logger().Error("failed to write report", "error", err)
+		os.Exit(1)
+	}
+	if out == "" {
+		if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+			util.Logger().Error("failed to write report", "error", err)
+			os.Exit(1)
+		}
+	} else if err := util.FileWriteAtomic(out, buf.Bytes(), 0644); err != nil {
+		util.Logger().Error("failed to write report", "error", err)
+		os.Exit(1)
+	}
+
+	expiring := 0
+	for _, item := range items {
+		if item.Expiring {
+			expiring++
+		}
+	}
+	if expiring != 0 {
+		util.Logger().Error("certificates expire within the threshold", "expiring", expiring, "within", within)
+		os.Exit(1)
+	}
+	util.Logger().Info("no certificate expires within the threshold", "certificates", len(items), "within", within)
+}
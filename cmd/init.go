@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	certgo "github.com/Alonza0314/cert-go"
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/util"
+	"github.com/spf13/cobra"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "used to create every certificate of the configuration",
+	Long:  "used to create every certificate of the configuration in issuer order, valid certificates are kept",
+	Run:   initPKI,
+}
+
+func init() {
+	initCmd.Flags().StringP("yaml", "y", "", "specify the configuration yaml file path")
+	initCmd.Flags().StringP("key", "k", "", "specify the type of the new private keys, <ecdsa> or <rsa>, overrides the key_type of the configuration")
+
+	cobra.CheckErr(initCmd.MarkFlagRequired("yaml"))
+
+	rootCmd.AddCommand(initCmd)
+}
+
+func initPKI(cmd *cobra.Command, args []string) {
+	yamlPath, err := cmd.Flags().GetString("yaml")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	keyType, err := cmd.Flags().GetString("key")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+
+	// an empty key type keeps the key_type of the configuration
+	var privateKeyType constants.PrivateKeyType
+	if keyType != "" {
+		if privateKeyType, err = util.ParsePrivateKeyType(keyType); err != nil {
+			util.Logger().Error(err.Error())
+			return
+		}
+	}
+
+	util.Logger().Info("start to init pki", "yaml", yamlPath)
+	items, err := certgo.InitPKI(yamlPath, privateKeyType)
+	printInitSummary(items)
+	if err != nil {
+		util.Logger().Error("failed to init pki", "error", err)
+		return
+	}
+	util.Logger().Info("init pki success")
+}
+
+func printInitSummary(items []certgo.InitItem) {
+	if len(items) == 0 {
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCERTIFICATE\tKEY\tCSR\tPATH")
+	counts := make(map[certgo.InitAction]int)
+	for _, item := range items {
+		counts[item.Action]++
+		action := string(item.Action)
+		if item.Reason != "" {
+			action += " (" + item.Reason + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", item.Name, action, created(item.KeyCreated), created(item.CsrCreated), item.CertPath)
+	}
+	_ = w.Flush()
+	fmt.Printf("%d created, %d reissued, %d skipped\n",
+		counts[certgo.INIT_ACTION_CREATED], counts[certgo.INIT_ACTION_REISSUED], counts[certgo.INIT_ACTION_SKIPPED])
+}
+
+func created(ok bool) string {
+	if ok {
+		return "created"
+	}
+	return "-"
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+
+	"github.com/Alonza0314/cert-go/util"
+	"github.com/spf13/cobra"
+)
+
+func setupLogger(cmd *cobra.Command, args []string) error {
+	quiet, err := cmd.Flags().GetBool("quiet")
+	if err != nil {
+		return err
+	}
+	verbose, err := cmd.Flags().GetBool("verbose")
+	if err != nil {
+		return err
+	}
+	format, err := cmd.Flags().GetString("log-format")
+	if err != nil {
+		return err
+	}
+	if quiet && verbose {
+		return errors.New("--quiet and --verbose cannot be used together")
+	}
+
+	level := slog.LevelInfo
+	if quiet {
+		level = slog.LevelError
+	} else if verbose {
+		level = slog.LevelDebug
+	}
+
+	var handler slog.Handler
+	switch format {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+			Level: level,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey && len(groups) == 0 {
+					return slog.String(slog.TimeKey, a.Value.Time().Format("2006-01-02 15:04:05"))
+				}
+				return a
+			},
+		})
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+	default:
+		return errors.New("invalid log format, please specify <text> or <json>")
+	}
+
+	util.SetLogger(slog.New(handler))
+	return nil
+}
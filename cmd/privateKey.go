@@ -4,8 +4,7 @@ import (
 	"strings"
 
 	certgo "github.com/Alonza0314/cert-go"
-	"github.com/Alonza0314/cert-go/constants"
-	logger "github.com/Alonza0314/logger-go"
+	"github.com/Alonza0314/cert-go/util"
 	"github.com/spf13/cobra"
 )
 
@@ -21,13 +20,9 @@ func init() {
 	privateKeyCmd.Flags().BoolP("force", "f", false, "overwrite the private key if it already exists")
 	privateKeyCmd.Flags().StringP("key", "k", "", "specify the type of the private key, <ecdsa> or <rsa>")
 
-	if err := privateKeyCmd.MarkFlagRequired("out"); err != nil {
-		logger.Error("cert-go", err.Error())
-	}
+	cobra.CheckErr(privateKeyCmd.MarkFlagRequired("out"))
 
-	if err := privateKeyCmd.MarkFlagRequired("key"); err != nil {
-		logger.Error("cert-go", err.Error())
-	}
+	cobra.CheckErr(privateKeyCmd.MarkFlagRequired("key"))
 
 	createCmd.AddCommand(privateKeyCmd)
 }
@@ -35,36 +30,37 @@ func init() {
 func createPrivateKey(cmd *cobra.Command, args []string) {
 	outputPath, err := cmd.Flags().GetString("out")
 	if err != nil {
-		logger.Error("cert-go", err.Error())
+		util.Logger().Error(err.Error())
 		return
 	}
 	force, err := cmd.Flags().GetBool("force")
 	if err != nil {
-		logger.Error("cert-go", err.Error())
+		util.Logger().Error(err.Error())
 		return
 	}
 
 	keyType, err := cmd.Flags().GetString("key")
 	if err != nil {
-		logger.Error("cert-go", err.Error())
+		util.Logger().Error(err.Error())
 		return
 	}
 
-	var privateKeyType constants.PrivateKeyType
-	if keyType == "rsa" {
-		privateKeyType = constants.PRIVATE_KEY_TYPE_RSA
-	} else {
-		privateKeyType = constants.PRIVATE_KEY_TYPE_ECDSA
+	privateKeyType, err := util.ParsePrivateKeyType(keyType)
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
 	}
 
-	logger.Info("cert-go", "start to create private key")
+	util.Logger().Info("start to create private key", "path", outputPath)
 	if _, err := certgo.CreatePrivateKey(outputPath, privateKeyType, force); err != nil {
 		if strings.Contains(err.Error(), "already exists") {
-			logger.Error("cert-go", "failed to create private key")
-			return
+			util.Logger().Error("use --force(f) to overwrite the private key")
+		}
+		if strings.Contains(err.Error(), "CA material") {
+			util.Logger().Error("use --force-ca to overwrite the CA private key")
 		}
-		logger.Error("cert-go", "failed to create private key")
+		util.Logger().Error("failed to create private key", "error", err)
 		return
 	}
-	logger.Info("cert-go", "create private key success")
+	util.Logger().Info("create private key success", "path", outputPath)
 }
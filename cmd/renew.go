@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"time"
+
+	certgo "github.com/Alonza0314/cert-go"
+	"github.com/Alonza0314/cert-go/util"
+	"github.com/spf13/cobra"
+)
+
+var renewCmd = &cobra.Command{
+	Use:   "renew <cert>",
+	Short: "used to renew a certificate",
+	Long:  "used to renew a certificate with the subject, subject alternative names and extensions of the existing one, signed by its issuer, the paths of the private key and the issuer are taken from the configuration yaml file or the flags",
+	Args:  cobra.ExactArgs(1),
+	Run:   renewCert,
+}
+
+func init() {
+	renewCmd.Flags().StringP("yaml", "y", "", "specify the configuration yaml file path to find the private key and the issuer of the certificate")
+	renewCmd.Flags().String("private-key", "", "specify the private key path of the certificate")
+	renewCmd.Flags().String("issuer-cert", "", "specify the certificate path of the issuer, not needed for a self-signed certificate")
+	renewCmd.Flags().String("issuer-key", "", "specify the private key path of the issuer, not needed for a self-signed certificate")
+	renewCmd.Flags().Bool("rotate-key", false, "replace the private key by a new one instead of reusing it")
+	renewCmd.Flags().StringP("key", "k", "", "specify the type of the new private key, <ecdsa> or <rsa>, the type of the current key by default")
+	renewCmd.Flags().Duration("window", 0, "only renew the certificate if it expires within this duration, e.g. 720h, always renew by default")
+	renewCmd.Flags().Duration("validity", 0, "specify the lifetime of the new certificate, e.g. 2160h, the lifetime of the current one by default")
+	renewCmd.Flags().Duration("backdate", 0, "move the not before of the new certificate back by this duration to tolerate clock skew")
+
+	renewCmd.MarkFlagsMutuallyExclusive("yaml", "private-key")
+	renewCmd.MarkFlagsRequiredTogether("issuer-cert", "issuer-key")
+
+	rootCmd.AddCommand(renewCmd)
+}
+
+func renewCert(cmd *cobra.Command, args []string) {
+	certPath := args[0]
+	flags := cmd.Flags()
+	yamlPath, err := flags.GetString("yaml")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+
+	var opts certgo.RenewOptions
+	if yamlPath != "" {
+		if opts, err = certgo.RenewOptionsFromConfig(yamlPath, certPath); err != nil {
+			util.Logger().Error("failed to renew cert", "error", err)
+			return
+		}
+	}
+	for flag, value := range map[string]*string{"private-key": &opts.KeyPath, "issuer-cert": &opts.IssuerCertPath, "issuer-key": &opts.IssuerKeyPath} {
+		if flags.Changed(flag) {
+			if *value, err = flags.GetString(flag); err != nil {
+				util.Logger().Error(err.Error())
+				return
+			}
+		}
+	}
+	if opts.RotateKey, err = flags.GetBool("rotate-key"); err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	keyType, err := flags.GetString("key")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	if keyType != "" {
+		if opts.KeyType, err = util.ParsePrivateKeyType(keyType); err != nil {
+			util.Logger().Error(err.Error())
+			return
+		}
+	}
+	for flag, value := range map[string]*time.Duration{"window": &opts.Window, "validity": &opts.Validity, "backdate": &opts.Backdate} {
+		if flags.Changed(flag) {
+			if *value, err = flags.GetDuration(flag); err != nil {
+				util.Logger().Error(err.Error())
+				return
+			}
+		}
+	}
+	if opts.KeyPath == "" {
+		util.Logger().Error("please specify the private key of the certificate with --yaml(y) or --private-key")
+		return
+	}
+
+	util.Logger().Info("start to renew cert", "path", certPath)
+	cert, renewed, err := certgo.RenewCertificate(certPath, opts)
+	if err != nil {
+		util.Logger().Error("failed to renew cert", "error", err)
+		return
+	}
+	if !renewed {
+		util.Logger().Info("cert does not need to be renewed", "path", certPath, "not_after", cert.NotAfter)
+		return
+	}
+	util.Logger().Info("renew cert success", "path", certPath, "not_after", cert.NotAfter)
+}
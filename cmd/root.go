@@ -1,14 +1,125 @@
 package cmd
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"strconv"
+	"strings"
 
+	certgo "github.com/Alonza0314/cert-go"
+	"github.com/Alonza0314/cert-go/constants"
 	"github.com/spf13/cobra"
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "cert-go",
-	Short: "cert-go is a tool to create and sign certificates",
+	Use:               "cert-go",
+	Short:             "cert-go is a tool to create and sign certificates",
+	PersistentPreRunE: setup,
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "only print errors")
+	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "print debug messages")
+	rootCmd.PersistentFlags().String("log-format", "text", "specify the log format, <text> or <json>")
+	rootCmd.PersistentFlags().String("format", "", "specify the format of the configuration file, <yaml>, <json> or <toml>, detected from the extension by default")
+	rootCmd.PersistentFlags().Bool("backup", false, "keep the overwritten or renewed files as <path>.<timestamp>.bak")
+	rootCmd.PersistentFlags().String("key-mode", "0600", "specify the permission of the private keys written, in octal")
+	rootCmd.PersistentFlags().String("key-owner", "", "specify the owner of the private keys written, a name or an id")
+	rootCmd.PersistentFlags().String("key-group", "", "specify the group of the private keys written, a name or an id")
+	rootCmd.PersistentFlags().Bool("strict-key-files", false, "refuse to read private keys readable by other users instead of warning")
+	rootCmd.PersistentFlags().String("audit-log", "", "append every key, csr and certificate written to the hash-chained audit log at this path")
+	rootCmd.PersistentFlags().Bool("force-ca", false, "confirm overwriting CA certificates and rotating CA keys, asked on a terminal otherwise")
+}
+
+func setup(cmd *cobra.Command, args []string) error {
+	if err := setupLogger(cmd, args); err != nil {
+		return err
+	}
+
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return err
+	}
+	switch constants.ConfigFormat(format) {
+	case "", constants.CONFIG_FORMAT_YAML, constants.CONFIG_FORMAT_JSON, constants.CONFIG_FORMAT_TOML:
+	default:
+		return errors.New("invalid config format, please specify <yaml>, <json> or <toml>")
+	}
+	certgo.SetConfigFormat(constants.ConfigFormat(format))
+
+	backup, err := cmd.Flags().GetBool("backup")
+	if err != nil {
+		return err
+	}
+	certgo.SetBackup(backup)
+
+	auditLog, err := cmd.Flags().GetString("audit-log")
+	if err != nil {
+		return err
+	}
+	certgo.SetAuditLog(auditLog)
+
+	forceCA, err := cmd.Flags().GetBool("force-ca")
+	if err != nil {
+		return err
+	}
+	certgo.SetCAOverwriteConfirm(caOverwriteConfirm(forceCA))
+
+	return setupKeyFiles(cmd)
+}
+
+// caOverwriteConfirm confirms every overwrite of CA material with force, asks for it on a terminal
+// otherwise and refuses it when stdin is not a terminal.
+func caOverwriteConfirm(force bool) func(path string) bool {
+	if force {
+		return func(string) bool { return true }
+	}
+	if !isTerminal(os.Stdin) {
+		return nil
+	}
+	return func(path string) bool {
+		p := prompter{reader: bufio.NewReader(os.Stdin)}
+		answer := p.ask(fmt.Sprintf("Overwrite CA material %s, every certificate it issued becomes invalid (y/N)", path), "")
+		return strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")
+	}
+}
+
+func setupKeyFiles(cmd *cobra.Command) error {
+	flags := cmd.Flags()
+	keyMode, err := flags.GetString("key-mode")
+	if err != nil {
+		return err
+	}
+	mode, err := strconv.ParseUint(keyMode, 8, 32)
+	if err != nil || mode == 0 || mode > 0777 {
+		return errors.New("invalid key mode, please specify an octal permission like 0600")
+	}
+	if mode&0400 == 0 {
+		return errors.New("invalid key mode, the owner must be able to read the private keys")
+	}
+	certgo.SetKeyFileMode(fs.FileMode(mode))
+
+	keyOwner, err := flags.GetString("key-owner")
+	if err != nil {
+		return err
+	}
+	keyGroup, err := flags.GetString("key-group")
+	if err != nil {
+		return err
+	}
+	if err := certgo.SetKeyFileOwner(keyOwner, keyGroup); err != nil {
+		return err
+	}
+
+	strict, err := flags.GetBool("strict-key-files")
+	if err != nil {
+		return err
+	}
+	certgo.SetStrictKeyFiles(strict)
+	return nil
 }
 
 func Execute() {
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"strings"
+
+	certgo "github.com/Alonza0314/cert-go"
+	"github.com/Alonza0314/cert-go/util"
+	"github.com/spf13/cobra"
+)
+
+var signCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "used to sign a csr provided by a third party",
+	Long:  "used to sign a csr provided by a third party, the subject and subject alternative names come from the csr and the validity and key usages from the profile, subject to the policy of the issuer",
+	Run:   signCsr,
+}
+
+func init() {
+	signCmd.Flags().StringP("yaml", "y", "", "specify the configuration yaml file path")
+	signCmd.Flags().String("csr", "", "specify the path of the csr to sign")
+	signCmd.Flags().StringP("issuer", "i", "", "specify the name of the issuer profile, the issuer of the profile by default")
+	signCmd.Flags().StringP("profile", "p", "", "specify the name of the certificate profile giving the type, validity and key usages")
+	signCmd.Flags().StringP("out", "o", "", "specify the output path of the certificate")
+	signCmd.Flags().BoolP("force", "f", false, "overwrite the certificate if it already exists")
+
+	cobra.CheckErr(signCmd.MarkFlagRequired("yaml"))
+	cobra.CheckErr(signCmd.MarkFlagRequired("csr"))
+	cobra.CheckErr(signCmd.MarkFlagRequired("profile"))
+	cobra.CheckErr(signCmd.MarkFlagRequired("out"))
+
+	rootCmd.AddCommand(signCmd)
+}
+
+func signCsr(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	yamlPath, err := flags.GetString("yaml")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	csrPath, err := flags.GetString("csr")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	issuer, err := flags.GetString("issuer")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	profile, err := flags.GetString("profile")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	out, err := flags.GetString("out")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+	force, err := flags.GetBool("force")
+	if err != nil {
+		util.Logger().Error(err.Error())
+		return
+	}
+
+	util.Logger().Info("start to sign csr", "csr", csrPath, "issuer", issuer, "profile", profile, "yaml", yamlPath)
+	if _, err := certgo.SignCsr(csrPath, out, issuer, profile, yamlPath, force); err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			util.Logger().Error("use --force(f) to overwrite the cert")
+		}
+		if strings.Contains(err.Error(), "CA material") {
+			util.Logger().Error("use --force-ca to overwrite the CA cert")
+		}
+		util.Logger().Error("failed to sign csr", "error", err)
+		return
+	}
+	util.Logger().Info("sign csr success", "path", out)
+}
@@ -0,0 +1,277 @@
+package certgo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+// StarterLeaf is an end-entity profile of a starter configuration.
+type StarterLeaf struct {
+	Name string
+	// Type is server, client or peer.
+	Type constants.CertType
+}
+
+// StarterConfig describes the configuration written by NewStarterConfig.
+type StarterConfig struct {
+	Organization string
+	// OutDir is the directory holding one sub directory per certificate.
+	OutDir      string
+	DNSNames    []string
+	IPAddresses []string
+	// KeyType is the key_type of every certificate, ecdsa or rsa, it is left unset when empty.
+	KeyType string
+	// Leaves are signed by the intermediate, server and peer leaves get DNSNames and IPAddresses.
+	Leaves []StarterLeaf
+}
+
+// NewStarterConfig returns a root, an intermediate and one profile per leaf.
+func NewStarterConfig(starter StarterConfig) (model.CAConfig, error) {
+	if starter.Organization == "" {
+		return model.CAConfig{}, errors.New("organization is required")
+	}
+	if starter.OutDir == "" {
+		return model.CAConfig{}, errors.New("output directory is required")
+	}
+	if starter.KeyType != "" {
+		if _, err := util.ParsePrivateKeyType(starter.KeyType); err != nil {
+			return model.CAConfig{}, err
+		}
+	}
+
+	paths := func(name string) model.Certificate {
+		dir := strings.TrimSuffix(filepath.ToSlash(starter.OutDir), "/") + "/" + name
+		return model.Certificate{
+			CertFilePath: dir + "/" + name + ".cert.pem",
+			KeyFilePath:  dir + "/" + name + ".key.pem",
+			CsrFilePath:  dir + "/" + name + ".csr.pem",
+			KeyType:      starter.KeyType,
+			Organization: starter.Organization,
+		}
+	}
+
+	var cfg model.CAConfig
+	cfg.CA.Root = paths(string(constants.CERT_TYPE_ROOT))
+	cfg.CA.Root.CsrFilePath = ""
+	cfg.CA.Root.Type = string(constants.CERT_TYPE_ROOT)
+	cfg.CA.Root.IsCA = true
+	cfg.CA.Root.CommonName = starter.Organization + " Root CA"
+	cfg.CA.Root.ValidityYears = 10
+
+	cfg.CA.Intermediate = paths(string(constants.CERT_TYPE_INTERMEDIATE))
+	cfg.CA.Intermediate.Type = string(constants.CERT_TYPE_INTERMEDIATE)
+	cfg.CA.Intermediate.ParentCertPath = cfg.CA.Root.CertFilePath
+	cfg.CA.Intermediate.ParentKeyPath = cfg.CA.Root.KeyFilePath
+	cfg.CA.Intermediate.IsCA = true
+	cfg.CA.Intermediate.CommonName = starter.Organization + " Intermediate CA"
+	cfg.CA.Intermediate.ValidityYears = 5
+
+	for _, leaf := range starter.Leaves {
+		if leaf.Name == "" {
+			return model.CAConfig{}, errors.New("leaf name is required")
+		}
+		if _, ok := cfg.CA.Profiles[leaf.Name]; ok || leaf.Name == string(constants.CERT_TYPE_ROOT) || leaf.Name == string(constants.CERT_TYPE_INTERMEDIATE) {
+			return model.CAConfig{}, fmt.Errorf("duplicate profile name: %s", leaf.Name)
+		}
+
+		cert := paths(leaf.Name)
+		cert.Type = string(leaf.Type)
+		cert.Issuer = string(constants.CERT_TYPE_INTERMEDIATE)
+		cert.CommonName = leaf.Name
+		cert.ValidityYears = 1
+		switch leaf.Type {
+		case constants.CERT_TYPE_SERVER, constants.CERT_TYPE_PEER:
+			cert.DNSNames = starter.DNSNames
+			cert.IPAddresses = starter.IPAddresses
+			if len(starter.DNSNames) != 0 {
+				cert.CommonName = starter.DNSNames[0]
+			}
+		case constants.CERT_TYPE_CLIENT:
+		default:
+			return model.CAConfig{}, fmt.Errorf("invalid leaf type %q for %s, expected server, client or peer", leaf.Type, leaf.Name)
+		}
+
+		if cfg.CA.Profiles == nil {
+			cfg.CA.Profiles = make(map[string]model.Certificate)
+		}
+		cfg.CA.Profiles[leaf.Name] = cert
+	}
+	return cfg, nil
+}
+
+// WriteStarterConfig writes the configuration built by NewStarterConfig to yamlPath,
+// in the format detected from its extension.
+func WriteStarterConfig(yamlPath string, starter StarterConfig, overwrite bool) (model.CAConfig, error) {
+	if util.FileExists(yamlPath) && !overwrite {
+		util.Logger().Error("configuration already exists", "path", yamlPath)
+		return model.CAConfig{}, errors.New("configuration already exists")
+	}
+
+	cfg, err := NewStarterConfig(starter)
+	if err != nil {
+		return model.CAConfig{}, err
+	}
+	if err := util.WriteStructToConfigFile(yamlPath, "", cfg); err != nil {
+		return model.CAConfig{}, err
+	}
+
+	util.Logger().Info("configuration written", "path", yamlPath, "profiles", len(cfg.CA.Profiles)+2)
+	return cfg, nil
+}
+
+// SetConfigFormat forces the format of the configuration files read and written by cert-go.
+// By default it is detected from the extension: json for .json, toml for .toml and yaml otherwise.
+// An empty format restores the detection.
+func SetConfigFormat(format constants.ConfigFormat) {
+	util.SetConfigFormat(format)
+}
+
+// ReadConfig reads the configuration file at yamlPath, in yaml, json or toml. ${VAR} is expanded in the paths and in
+// the organization and common name, and a leading ~ in the paths is replaced by the home directory.
+// Relative paths are then resolved against the directory of the yaml file, unless relative_to_cwd is set.
+func ReadConfig(yamlPath string) (model.CAConfig, error) {
+	var cfg model.CAConfig
+	if err := util.ReadConfigFileToStruct(yamlPath, "", &cfg); err != nil {
+		return model.CAConfig{}, err
+	}
+
+	baseDir := ""
+	if !cfg.RelativeToCwd {
+		baseDir = filepath.Dir(yamlPath)
+	}
+	if err := expandConfig(&cfg, baseDir); err != nil {
+		util.Logger().Error("failed to expand configuration", "path", yamlPath, "error", err)
+		return model.CAConfig{}, err
+	}
+	return cfg, nil
+}
+
+// expandConfig expands the certificates and the agent hooks of cfg in place, relative paths are joined to baseDir when it is not empty.
+// Every certificate gets its name, the hooks that apply to it and the policy of its issuer.
+func expandConfig(cfg *model.CAConfig, baseDir string) error {
+	certs := map[string]*model.Certificate{
+		string(constants.CERT_TYPE_ROOT):         &cfg.CA.Root,
+		string(constants.CERT_TYPE_INTERMEDIATE): &cfg.CA.Intermediate,
+		string(constants.CERT_TYPE_SERVER):       &cfg.CA.Server,
+		string(constants.CERT_TYPE_CLIENT):       &cfg.CA.Client,
+		string(constants.CERT_TYPE_PEER):         &cfg.CA.Peer,
+	}
+	for name, cert := range cfg.CA.Profiles {
+		if err := expandCertificate(&cert, baseDir); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+		cert.Name, cert.Hooks = name, certificateHooks(cfg.Hooks, name)
+		cfg.CA.Profiles[name] = cert
+	}
+	for name, cert := range certs {
+		if err := expandCertificate(cert, baseDir); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		cert.Name, cert.Hooks = name, certificateHooks(cfg.Hooks, name)
+	}
+	// the issuers are found by their expanded paths
+	for name, cert := range cfg.CA.Profiles {
+		cert.IssuerPolicy = issuerPolicy(cfg.CA, cert)
+		cfg.CA.Profiles[name] = cert
+	}
+	for _, cert := range certs {
+		cert.IssuerPolicy = issuerPolicy(cfg.CA, *cert)
+	}
+	for i, hook := range cfg.Agent.Hooks {
+		if hook.SignalPidFile == "" {
+			continue
+		}
+		value, err := expandPath(hook.SignalPidFile, baseDir)
+		if err != nil {
+			return fmt.Errorf("agent hook %d: %w", i+1, err)
+		}
+		cfg.Agent.Hooks[i].SignalPidFile = value
+	}
+	return nil
+}
+
+// issuerPolicy returns the policy of the issuer of cert, found by its issuer name or else by its parent_cert path.
+func issuerPolicy(ca model.CertificateAuthority, cert model.Certificate) *model.Policy {
+	if cert.Issuer != "" {
+		issuer, _ := ca.Profile(cert.Issuer)
+		return issuer.Policy
+	}
+	if cert.ParentCertPath == "" {
+		return nil
+	}
+	issuers := []model.Certificate{ca.Root, ca.Intermediate, ca.Server, ca.Client, ca.Peer}
+	for _, issuer := range ca.Profiles {
+		issuers = append(issuers, issuer)
+	}
+	for _, issuer := range issuers {
+		if issuer.CertFilePath == cert.ParentCertPath && issuer.Policy != nil {
+			return issuer.Policy
+		}
+	}
+	return nil
+}
+
+func expandCertificate(cert *model.Certificate, baseDir string) error {
+	for _, field := range []*string{&cert.Organization, &cert.CommonName} {
+		value, err := expandEnv(*field)
+		if err != nil {
+			return err
+		}
+		*field = value
+	}
+
+	for _, path := range []*string{&cert.CertFilePath, &cert.KeyFilePath, &cert.CsrFilePath, &cert.ParentCertPath, &cert.ParentKeyPath} {
+		if *path == "" {
+			continue
+		}
+		value, err := expandPath(*path, baseDir)
+		if err != nil {
+			return err
+		}
+		*path = value
+	}
+	return nil
+}
+
+// expandPath expands the environment variables and a leading ~ of path, and joins it to baseDir
+// when it is relative and baseDir is not empty.
+func expandPath(path, baseDir string) (string, error) {
+	value, err := expandEnv(path)
+	if err != nil {
+		return "", err
+	}
+	if value == "~" || strings.HasPrefix(value, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		value = filepath.Join(home, value[1:])
+	}
+	if baseDir != "" && !filepath.IsAbs(value) {
+		value = filepath.Join(baseDir, value)
+	}
+	return value, nil
+}
+
+// expandEnv replaces ${VAR} and $VAR in s by the value of the environment variable, which must be set.
+func expandEnv(s string) (string, error) {
+	var missing []string
+	expanded := os.Expand(s, func(name string) string {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) != 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
@@ -0,0 +1,227 @@
+package certgo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+func TestWriteStarterConfig(t *testing.T) {
+	yamlPath := "./default_ca/starterCfg.yml"
+	starter := StarterConfig{
+		Organization: "starter",
+		OutDir:       "./default_ca/starter/",
+		DNSNames:     []string{"starter.test"},
+		IPAddresses:  []string{"127.0.0.1"},
+		Leaves: []StarterLeaf{
+			{Name: "web", Type: constants.CERT_TYPE_SERVER},
+			{Name: "worker", Type: constants.CERT_TYPE_CLIENT},
+			{Name: "node", Type: constants.CERT_TYPE_PEER},
+		},
+	}
+
+	expect, err := WriteStarterConfig(yamlPath, starter, false)
+	if err != nil {
+		t.Fatalf("TestWriteStarterConfig: %v", err)
+	}
+	if _, err := WriteStarterConfig(yamlPath, starter, false); err == nil {
+		t.Fatalf("TestWriteStarterConfig: expected error for existing configuration without force")
+	}
+
+	var cfg model.CAConfig
+	if err := util.ReadYamlFileToStruct(yamlPath, &cfg); err != nil {
+		t.Fatalf("TestWriteStarterConfig: %v", err)
+	}
+	if !reflect.DeepEqual(cfg, expect) {
+		t.Fatalf("TestWriteStarterConfig: actual %v != expect %v", cfg, expect)
+	}
+
+	web, err := ResolveProfile(cfg.CA, "web")
+	if err != nil {
+		t.Fatalf("TestWriteStarterConfig: %v", err)
+	}
+	if web.CertFilePath != "./default_ca/starter/web/web.cert.pem" || web.ParentCertPath != cfg.CA.Intermediate.CertFilePath {
+		t.Fatalf("TestWriteStarterConfig: unexpected paths %s, %s", web.CertFilePath, web.ParentCertPath)
+	}
+	if !reflect.DeepEqual(web.DNSNames, starter.DNSNames) {
+		t.Fatalf("TestWriteStarterConfig: unexpected dns names %v", web.DNSNames)
+	}
+	if worker := cfg.CA.Profiles["worker"]; len(worker.DNSNames) != 0 {
+		t.Fatalf("TestWriteStarterConfig: client has dns names %v", worker.DNSNames)
+	}
+
+	if err := util.FileDelete(yamlPath); err != nil {
+		t.Fatalf("TestWriteStarterConfig: %v", err)
+	}
+}
+
+var testCaseNewStarterConfigError = []struct {
+	name    string
+	starter StarterConfig
+}{
+	{
+		name:    "missing organization",
+		starter: StarterConfig{OutDir: "./ca"},
+	},
+	{
+		name:    "missing output directory",
+		starter: StarterConfig{Organization: "starter"},
+	},
+	{
+		name:    "unknown key type",
+		starter: StarterConfig{Organization: "starter", OutDir: "./ca", KeyType: "dsa"},
+	},
+	{
+		name: "duplicate leaf",
+		starter: StarterConfig{Organization: "starter", OutDir: "./ca", Leaves: []StarterLeaf{
+			{Name: "web", Type: constants.CERT_TYPE_SERVER},
+			{Name: "web", Type: constants.CERT_TYPE_CLIENT},
+		}},
+	},
+	{
+		name: "leaf named after the intermediate",
+		starter: StarterConfig{Organization: "starter", OutDir: "./ca", Leaves: []StarterLeaf{
+			{Name: "intermediate", Type: constants.CERT_TYPE_SERVER},
+		}},
+	},
+	{
+		name: "ca leaf",
+		starter: StarterConfig{Organization: "starter", OutDir: "./ca", Leaves: []StarterLeaf{
+			{Name: "sub", Type: constants.CERT_TYPE_INTERMEDIATE},
+		}},
+	},
+}
+
+func TestNewStarterConfigError(t *testing.T) {
+	for _, testCase := range testCaseNewStarterConfigError {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := NewStarterConfig(testCase.starter); err == nil {
+				t.Fatalf("TestNewStarterConfigError (%s): expected error", testCase.name)
+			}
+		})
+	}
+}
+
+var testCaseReadConfig = []struct {
+	name   string
+	file   string
+	yaml   string
+	expect model.Certificate
+}{
+	{
+		name: "relative to the yaml file",
+		file: "cfg.yml",
+		yaml: `ca:
+  root:
+    cert: ./root/root.cert.pem
+    private_key: ${CERT_GO_TEST_DIR}/root.key.pem
+    organization: ${CERT_GO_TEST_ORG}
+    common_name: ${CERT_GO_TEST_ORG} Root CA
+`,
+		expect: model.Certificate{
+			Name:         "root",
+			CertFilePath: "default_ca/readConfig/root/root.cert.pem",
+			KeyFilePath:  "default_ca/readConfig/keys/root.key.pem",
+			Organization: "acme",
+			CommonName:   "acme Root CA",
+		},
+	},
+	{
+		name: "relative to the working directory",
+		file: "cfg.yml",
+		yaml: `relative_to_cwd: true
+ca:
+  root:
+    cert: ./root/root.cert.pem
+    private_key: /etc/cert-go/root.key.pem
+    parent_cert: ~/root.cert.pem
+`,
+		expect: model.Certificate{
+			Name:           "root",
+			CertFilePath:   "./root/root.cert.pem",
+			KeyFilePath:    "/etc/cert-go/root.key.pem",
+			ParentCertPath: "/home/cert-go/root.cert.pem",
+		},
+	},
+	{
+		name: "json",
+		file: "cfg.json",
+		yaml: `{"ca": {"root": {"cert": "root.cert.pem", "organization": "${CERT_GO_TEST_ORG}"}}}`,
+		expect: model.Certificate{
+			Name:         "root",
+			CertFilePath: "default_ca/readConfig/root.cert.pem",
+			Organization: "acme",
+		},
+	},
+	{
+		name: "toml",
+		file: "cfg.toml",
+		yaml: "[ca.root]\ncert = \"root.cert.pem\"\nis_ca = true\nvalidity_years = 10\n",
+		expect: model.Certificate{
+			Name:          "root",
+			CertFilePath:  "default_ca/readConfig/root.cert.pem",
+			IsCA:          true,
+			ValidityYears: 10,
+		},
+	},
+	{
+		name: "hooks",
+		file: "cfg.yml",
+		yaml: `hooks:
+  - events: [cert_issued]
+    exec: [systemctl, reload, nginx]
+  - events: [cert_issued]
+    certificates: [server]
+    exec: [cp, "{{.CertPath}}", /etc/nginx/]
+ca:
+  root:
+    cert: root.cert.pem
+`,
+		expect: model.Certificate{
+			Name:         "root",
+			CertFilePath: "default_ca/readConfig/root.cert.pem",
+			Hooks:        []model.Hook{{Events: []string{"cert_issued"}, Exec: []string{"systemctl", "reload", "nginx"}}},
+		},
+	},
+}
+
+func TestReadConfig(t *testing.T) {
+	t.Setenv("CERT_GO_TEST_DIR", "keys")
+	t.Setenv("CERT_GO_TEST_ORG", "acme")
+	t.Setenv("HOME", "/home/cert-go")
+
+	for _, testCase := range testCaseReadConfig {
+		t.Run(testCase.name, func(t *testing.T) {
+			yamlPath := "./default_ca/readConfig/" + testCase.file
+			if err := util.FileDirCreate(yamlPath); err != nil {
+				t.Fatalf("TestReadConfig (%s): %v", testCase.name, err)
+			}
+			if err := util.FileWrite(yamlPath, []byte(testCase.yaml), 0644); err != nil {
+				t.Fatalf("TestReadConfig (%s): %v", testCase.name, err)
+			}
+			defer func() {
+				if err := util.FileDelete(yamlPath); err != nil {
+					t.Fatalf("TestReadConfig (%s): %v", testCase.name, err)
+				}
+			}()
+
+			cfg, err := ReadConfig(yamlPath)
+			if err != nil {
+				t.Fatalf("TestReadConfig (%s): %v", testCase.name, err)
+			}
+			if !reflect.DeepEqual(cfg.CA.Root, testCase.expect) {
+				t.Fatalf("TestReadConfig (%s): actual %v != expect %v", testCase.name, cfg.CA.Root, testCase.expect)
+			}
+		})
+	}
+
+	t.Run("unset variable", func(t *testing.T) {
+		cfg := model.CAConfig{CA: model.CertificateAuthority{Root: model.Certificate{CertFilePath: "${CERT_GO_TEST_UNSET}/root.cert.pem"}}}
+		if err := expandConfig(&cfg, ""); err == nil || err.Error() != "root: environment variable CERT_GO_TEST_UNSET is not set" {
+			t.Fatalf("TestReadConfig: unexpected error %v", err)
+		}
+	})
+}
@@ -2,15 +2,36 @@ package constants
 
 type CertType string
 type PrivateKeyType string
+type ConfigFormat string
+type ReportFormat string
+type HookEvent string
+type HookFailure string
 
 const (
 	CERT_TYPE_ROOT         CertType = "root"
 	CERT_TYPE_INTERMEDIATE CertType = "intermediate"
 	CERT_TYPE_SERVER       CertType = "server"
 	CERT_TYPE_CLIENT       CertType = "client"
+	CERT_TYPE_PEER         CertType = "peer"
 
 	PRIVATE_KEY_TYPE_ECDSA   PrivateKeyType = "EC PRIVATE KEY"
 	PRIVATE_KEY_TYPE_RSA     PrivateKeyType = "RSA PRIVATE KEY"
 	PRIVATE_KEY_TYPE_UNKNOWN PrivateKeyType = "UNKNOWN"
 	PRIVATE_KEY_LENGTH       int            = 4096
+
+	CONFIG_FORMAT_YAML ConfigFormat = "yaml"
+	CONFIG_FORMAT_JSON ConfigFormat = "json"
+	CONFIG_FORMAT_TOML ConfigFormat = "toml"
+
+	REPORT_FORMAT_TABLE      ReportFormat = "table"
+	REPORT_FORMAT_JSON       ReportFormat = "json"
+	REPORT_FORMAT_PROMETHEUS ReportFormat = "prometheus"
+
+	HOOK_EVENT_KEY_CREATED  HookEvent = "key_created"
+	HOOK_EVENT_CSR_CREATED  HookEvent = "csr_created"
+	HOOK_EVENT_CERT_ISSUED  HookEvent = "cert_issued"
+	HOOK_EVENT_CERT_RENEWED HookEvent = "cert_renewed"
+
+	HOOK_FAILURE_IGNORE HookFailure = "ignore"
+	HOOK_FAILURE_ABORT  HookFailure = "abort"
 )
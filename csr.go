@@ -1,45 +1,48 @@
 package certgo
 
 import (
-	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"errors"
-	"fmt"
 
 	"github.com/Alonza0314/cert-go/constants"
 	"github.com/Alonza0314/cert-go/model"
 	"github.com/Alonza0314/cert-go/util"
-	logger "github.com/Alonza0314/logger-go"
 )
 
+// CreateCsr creates the csr of cfg, and its private key if it does not exist. The private key
+// is of keyType, or of the key_type of cfg when keyType is empty.
 func CreateCsr(cfg model.Certificate, keyType constants.PrivateKeyType, overwrite bool) (*x509.CertificateRequest, error) {
-	logger.Info("CreateCsr", "creating csr")
+	util.Logger().Debug("creating csr", "path", cfg.CsrFilePath)
+
+	keyType, err := certificateKeyType(cfg, keyType)
+	if err != nil {
+		return nil, err
+	}
 
 	// check csr exists
 	if util.FileExists(cfg.CsrFilePath) {
 		if !overwrite {
-			logger.Error("CreateCsr", fmt.Sprintf("CSR already exists at %s.", cfg.CertFilePath))
+			util.Logger().Error("csr already exists", "path", cfg.CsrFilePath)
 			return nil, errors.New("csr already exists")
 		}
-		logger.Warn("CreateCsr", "CSR already exists. Overwrite it")
-		if err := util.FileDelete(cfg.CsrFilePath); err != nil {
-			logger.Error("CreateCsr", "failed to remove existing CSR: "+err.Error())
-			return nil, err
-		}
+		// replaced only once the new csr is written
+		util.Logger().Warn("csr already exists, overwrite it", "path", cfg.CsrFilePath)
 	}
 
 	var privateKey interface{}
-	var err error
 
 	// check private key exists
 	if !util.FileExists(cfg.KeyFilePath) {
-		logger.Warn("CreateCsr", "private key does not exist")
-		privateKey, err = CreatePrivateKey(cfg.KeyFilePath, keyType, overwrite)
+		util.Logger().Warn("private key does not exist, creating", "path", cfg.KeyFilePath)
+		privateKey, err = createPrivateKey(cfg.KeyFilePath, keyType, overwrite)
 		if err != nil {
 			return nil, err
 		}
+		if err := recordKeyCreated(cfg, privateKey); err != nil {
+			return nil, err
+		}
 	}
 
 	if privateKey == nil {
@@ -51,7 +54,7 @@ func CreateCsr(cfg model.Certificate, keyType constants.PrivateKeyType, overwrit
 
 	// check private key type is same as the key type
 	if _, err := util.IsPrivateKeyTypeSame(privateKey, keyType); err != nil {
-		logger.Error("CreateCsr", err.Error())
+		util.Logger().Error(err.Error(), "path", cfg.KeyFilePath)
 		return nil, err
 	}
 
@@ -63,9 +66,9 @@ func CreateCsr(cfg model.Certificate, keyType constants.PrivateKeyType, overwrit
 	}
 
 	// create csr
-	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, template, privateKey)
+	csrBytes, err := x509.CreateCertificateRequest(util.RandReader(), template, util.Signer(privateKey))
 	if err != nil {
-		logger.Error("CreateCsr", err.Error())
+		util.Logger().Error("failed to create csr", "error", err)
 		return nil, err
 	}
 
@@ -77,23 +80,26 @@ func CreateCsr(cfg model.Certificate, keyType constants.PrivateKeyType, overwrit
 
 	// create directory exists
 	if !util.FileDirExists(cfg.CsrFilePath) {
-		logger.Warn("CreateCsr", util.FileDir(cfg.CsrFilePath)+" directory not exists, creating...")
+		util.Logger().Warn("directory not exists, creating", "path", util.FileDir(cfg.CsrFilePath))
 		if err := util.FileDirCreate(cfg.CsrFilePath); err != nil {
 			return nil, err
 		}
-		logger.Info("CreateCsr", util.FileDir(cfg.CsrFilePath)+" directory created")
+		util.Logger().Debug("directory created", "path", util.FileDir(cfg.CsrFilePath))
 	}
 
 	// save csr
-	if err := util.FileWrite(cfg.CsrFilePath, csrPEM, 0644); err != nil {
+	if err := util.FileWriteAtomic(cfg.CsrFilePath, csrPEM, 0644); err != nil {
 		return nil, err
 	}
 
-	logger.Info("CreateCsr", "csr created")
-
 	csr, err := x509.ParseCertificateRequest(csrBytes)
 	if err != nil {
-		logger.Error("CreateCsr", err.Error())
+		util.Logger().Error("failed to parse csr", "error", err)
+		return nil, err
+	}
+
+	util.Logger().Info("csr created", "path", cfg.CsrFilePath, "subject", csr.Subject.String())
+	if err := recordEvent(cfg.Hooks, newHookPayload(constants.HOOK_EVENT_CSR_CREATED, cfg, nil, csr)); err != nil {
 		return nil, err
 	}
 	return csr, nil
@@ -0,0 +1,156 @@
+package certgo
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+// ExpiryItem is a certificate found by ScanExpiry.
+type ExpiryItem struct {
+	Path          string    `json:"path"`
+	Subject       string    `json:"subject"`
+	Issuer        string    `json:"issuer"`
+	Serial        string    `json:"serial"`
+	NotAfter      time.Time `json:"not_after"`
+	DaysRemaining int       `json:"days_remaining"`
+	// Expiring is true when the certificate expires within the threshold, or has expired.
+	Expiring bool `json:"expiring"`
+}
+
+// certificate files without a PEM header are only parsed as DER with one of these extensions
+var derExtensions = map[string]bool{".der": true, ".cer": true, ".crt": true}
+
+// ScanExpiry walks the directories and returns every certificate found in them, the one expiring first
+// first. PEM files may hold several certificates, other files such as private keys, CSRs and the backups
+// of replaced files are ignored.
+func ScanExpiry(dirs []string, within time.Duration) ([]ExpiryItem, error) {
+	now := util.Now()
+	var items []ExpiryItem
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				util.Logger().Error("failed to walk directory", "path", path, "error", err)
+				return err
+			}
+			// backups hold replaced certificates, they would be reported as expiring
+			if !d.Type().IsRegular() || filepath.Ext(path) == util.BackupExtension {
+				return nil
+			}
+			certs, err := readCertificates(path)
+			if err != nil {
+				return err
+			}
+			for _, cert := range certs {
+				remaining := cert.NotAfter.Sub(now)
+				items = append(items, ExpiryItem{
+					Path:          path,
+					Subject:       cert.Subject.String(),
+					Issuer:        cert.Issuer.String(),
+					Serial:        cert.SerialNumber.Text(16),
+					NotAfter:      cert.NotAfter,
+					DaysRemaining: int(math.Floor(remaining.Hours() / 24)),
+					Expiring:      remaining <= within,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].NotAfter.Before(items[j].NotAfter) })
+	util.Logger().Debug("expiry scanned", "dirs", dirs, "certificates", len(items))
+	return items, nil
+}
+
+// readCertificates returns the certificates of the file, none if it holds no certificate or cannot be read.
+func readCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// private keys are only readable by their owner, a monitoring user cannot read them
+		util.Logger().Warn("failed to read file, skip it", "path", path, "error", err)
+		return nil, nil
+	}
+
+	certs, err := util.ParseCertificates(data, derExtensions[strings.ToLower(filepath.Ext(path))])
+	if err != nil {
+		util.Logger().Warn("failed to parse certificate, skip it", "path", path, "error", err)
+		return nil, nil
+	}
+	return certs, nil
+}
+
+// WriteExpiryReport writes the items in the format: a table, a JSON array or the Prometheus text format
+// of the node exporter textfile collector.
+func WriteExpiryReport(w io.Writer, items []ExpiryItem, format constants.ReportFormat) error {
+	switch format {
+	case constants.REPORT_FORMAT_TABLE, "":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "DAYS\tNOT AFTER\tSUBJECT\tISSUER\tSERIAL\tPATH")
+		for _, item := range items {
+			days := fmt.Sprint(item.DaysRemaining)
+			if item.Expiring {
+				days += " !"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", days, item.NotAfter.UTC().Format(time.RFC3339), item.Subject, item.Issuer, item.Serial, item.Path)
+		}
+		return tw.Flush()
+	case constants.REPORT_FORMAT_JSON:
+		if items == nil {
+			items = []ExpiryItem{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	case constants.REPORT_FORMAT_PROMETHEUS:
+		var b strings.Builder
+		b.WriteString("# HELP certgo_certificate_not_after_seconds Expiry of the certificate as a unix timestamp.\n")
+		b.WriteString("# TYPE certgo_certificate_not_after_seconds gauge\n")
+		for _, item := range items {
+			fmt.Fprintf(&b, "certgo_certificate_not_after_seconds{%s} %d\n", prometheusLabels(item), item.NotAfter.Unix())
+		}
+		b.WriteString("# HELP certgo_certificate_days_remaining Whole days until the certificate expires, negative once expired.\n")
+		b.WriteString("# TYPE certgo_certificate_days_remaining gauge\n")
+		for _, item := range items {
+			fmt.Fprintf(&b, "certgo_certificate_days_remaining{%s} %d\n", prometheusLabels(item), item.DaysRemaining)
+		}
+		b.WriteString("# HELP certgo_certificate_expiring Whether the certificate expires within the threshold.\n")
+		b.WriteString("# TYPE certgo_certificate_expiring gauge\n")
+		for _, item := range items {
+			expiring := 0
+			if item.Expiring {
+				expiring = 1
+			}
+			fmt.Fprintf(&b, "certgo_certificate_expiring{%s} %d\n", prometheusLabels(item), expiring)
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	default:
+		return fmt.Errorf("unknown report format: %q, expected table, json or prometheus", format)
+	}
+}
+
+var prometheusEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func prometheusLabels(item ExpiryItem) string {
+	return fmt.Sprintf(`path="%s",subject="%s",issuer="%s",serial="%s"`,
+		prometheusEscaper.Replace(item.Path),
+		prometheusEscaper.Replace(item.Subject),
+		prometheusEscaper.Replace(item.Issuer),
+		prometheusEscaper.Replace(item.Serial),
+	)
+}
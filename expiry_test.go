@@ -0,0 +1,147 @@
+package certgo
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+func TestScanExpiry(t *testing.T) {
+	SetClock(FixedClock(DeterministicEpoch))
+	defer SetClock(nil)
+
+	dir := t.TempDir()
+	key, err := GeneratePrivateKey(constants.PRIVATE_KEY_TYPE_ECDSA)
+	if err != nil {
+		t.Fatalf("TestScanExpiry: %v", err)
+	}
+	root, err := IssueCertificate(model.Certificate{Type: "root", IsCA: true, CommonName: "root", Organization: "acme", ValidityYears: 10}, key.(crypto.Signer).Public(), nil, key)
+	if err != nil {
+		t.Fatalf("TestScanExpiry: %v", err)
+	}
+	leaf, err := IssueCertificate(model.Certificate{Type: "server", CommonName: "leaf", Organization: "acme", ValidityDay: 10}, key.(crypto.Signer).Public(), root, key)
+	if err != nil {
+		t.Fatalf("TestScanExpiry: %v", err)
+	}
+	keyPEM, err := util.EncodePrivateKeyPEM(key)
+	if err != nil {
+		t.Fatalf("TestScanExpiry: %v", err)
+	}
+	files := map[string][]byte{
+		"root.cert.pem":   util.EncodeCertificatePEM(root),
+		"sub/bundle.pem":  append(util.EncodeCertificatePEM(leaf), util.EncodeCertificatePEM(root)...),
+		"sub/leaf.der":    leaf.Raw,
+		"sub/leaf.key":    keyPEM,
+		"sub/notes.txt":   []byte("not a certificate"),
+		"sub/garbage.crt": []byte("not a certificate"),
+		// backups of replaced certificates are not reported
+		"sub/leaf.cert.pem.20240101T000000Z.bak": util.EncodeCertificatePEM(leaf),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := util.FileDirCreate(path); err != nil {
+			t.Fatalf("TestScanExpiry: %v", err)
+		}
+		if err := util.FileWrite(path, data, 0644); err != nil {
+			t.Fatalf("TestScanExpiry: %v", err)
+		}
+	}
+
+	items, err := ScanExpiry([]string{dir}, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("TestScanExpiry: %v", err)
+	}
+	if len(items) != 4 {
+		t.Fatalf("TestScanExpiry: expected 4 certificates, got %d: %+v", len(items), items)
+	}
+	for i, item := range items {
+		isLeaf := strings.HasPrefix(item.Subject, "CN=leaf")
+		if isLeaf != (i < 2) {
+			t.Fatalf("TestScanExpiry: certificates are not sorted by expiry: %+v", items)
+		}
+		if item.Expiring != isLeaf {
+			t.Fatalf("TestScanExpiry: %s expiring %v != %v", item.Path, item.Expiring, isLeaf)
+		}
+		if isLeaf && item.DaysRemaining != 10 {
+			t.Fatalf("TestScanExpiry: %s days remaining %d != 10", item.Path, item.DaysRemaining)
+		}
+	}
+
+	if _, err := ScanExpiry([]string{filepath.Join(dir, "missing")}, 0); err == nil {
+		t.Fatalf("TestScanExpiry: expected error for a missing directory")
+	}
+
+	var buf bytes.Buffer
+	if err := WriteExpiryReport(&buf, items, constants.REPORT_FORMAT_JSON); err != nil {
+		t.Fatalf("TestScanExpiry: %v", err)
+	}
+	var decoded []ExpiryItem
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != len(items) {
+		t.Fatalf("TestScanExpiry: invalid json report: %v", err)
+	}
+
+	buf.Reset()
+	if err := WriteExpiryReport(&buf, items[:1], constants.REPORT_FORMAT_PROMETHEUS); err != nil {
+		t.Fatalf("TestScanExpiry: %v", err)
+	}
+	labels := `{path="` + items[0].Path + `",subject="CN=leaf,O=acme",issuer="CN=root,O=acme",serial="` + items[0].Serial + `"}`
+	for _, expected := range []string{
+		"certgo_certificate_expiring" + labels + " 1",
+		"certgo_certificate_days_remaining" + labels + " " + fmt.Sprint(items[0].DaysRemaining),
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Fatalf("TestScanExpiry: prometheus report does not contain %s:\n%s", expected, buf.String())
+		}
+	}
+
+	if err := WriteExpiryReport(&buf, items, "xml"); err == nil {
+		t.Fatalf("TestScanExpiry: expected error for an unknown report format")
+	}
+}
+
+func TestScanExpiryUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	key, err := GeneratePrivateKey(constants.PRIVATE_KEY_TYPE_ECDSA)
+	if err != nil {
+		t.Fatalf("TestScanExpiryUnreadable: %v", err)
+	}
+	root, err := IssueCertificate(model.Certificate{Type: "root", IsCA: true, CommonName: "root", ValidityYears: 10}, key.(crypto.Signer).Public(), nil, key)
+	if err != nil {
+		t.Fatalf("TestScanExpiryUnreadable: %v", err)
+	}
+	keyPEM, err := util.EncodePrivateKeyPEM(key)
+	if err != nil {
+		t.Fatalf("TestScanExpiryUnreadable: %v", err)
+	}
+	if err := util.FileWrite(filepath.Join(dir, "root.cert.pem"), util.EncodeCertificatePEM(root), 0644); err != nil {
+		t.Fatalf("TestScanExpiryUnreadable: %v", err)
+	}
+	keyPath := filepath.Join(dir, "root.key.pem")
+	if err := util.FileWrite(keyPath, keyPEM, 0000); err != nil {
+		t.Fatalf("TestScanExpiryUnreadable: %v", err)
+	}
+	if err := os.Chmod(keyPath, 0000); err != nil {
+		t.Fatalf("TestScanExpiryUnreadable: %v", err)
+	}
+	if _, err := os.ReadFile(keyPath); err == nil {
+		t.Skip("TestScanExpiryUnreadable: the private key is readable despite its mode, e.g. when running as root")
+	}
+
+	items, err := ScanExpiry([]string{dir}, 0)
+	if err != nil {
+		t.Fatalf("TestScanExpiryUnreadable: unreadable private key stopped the scan: %v", err)
+	}
+	if len(items) != 1 || items[0].Path != filepath.Join(dir, "root.cert.pem") {
+		t.Fatalf("TestScanExpiryUnreadable: expected the root certificate only, got %+v", items)
+	}
+}
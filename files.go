@@ -0,0 +1,56 @@
+package certgo
+
+import (
+	"fmt"
+	"io/fs"
+	"sync/atomic"
+
+	"github.com/Alonza0314/cert-go/util"
+)
+
+// SetBackup keeps the private key, csr or certificate replaced by an overwrite or a renewal as a
+// timestamped backup next to it, <path>.<timestamp>.bak. Backups are disabled by default.
+func SetBackup(enabled bool) {
+	util.SetBackup(enabled)
+}
+
+// SetKeyFileMode sets the permission of the private keys written by cert-go, 0600 by default.
+// Pass 0 to restore the default.
+func SetKeyFileMode(mode fs.FileMode) {
+	util.SetKeyFileMode(mode)
+}
+
+// SetKeyFileOwner sets the owner and group, names or numeric ids, of the private keys written by
+// cert-go. An empty owner or group leaves it to the one of the process.
+func SetKeyFileOwner(owner, group string) error {
+	return util.SetKeyFileOwner(owner, group)
+}
+
+// SetStrictKeyFiles refuses to read private keys the group or others can access beyond the key file
+// mode, instead of only logging a warning.
+func SetStrictKeyFiles(strict bool) {
+	util.SetStrictKeyFiles(strict)
+}
+
+var caOverwriteConfirm atomic.Pointer[func(path string) bool]
+
+// SetCAOverwriteConfirm sets the function asked to confirm the overwrite of CA material, the
+// certificate of a CA or its private key on a renewal rotating it. Replacing it invalidates every
+// certificate it issued, so the overwrite is refused when confirm is nil, the default, or returns false.
+func SetCAOverwriteConfirm(confirm func(path string) bool) {
+	if confirm == nil {
+		caOverwriteConfirm.Store(nil)
+		return
+	}
+	caOverwriteConfirm.Store(&confirm)
+}
+
+// confirmCAOverwrite returns an error unless the overwrite of the CA material at path is confirmed.
+func confirmCAOverwrite(path string) error {
+	if confirm := caOverwriteConfirm.Load(); confirm != nil && (*confirm)(path) {
+		util.Logger().Warn("CA material overwrite confirmed", "path", path)
+		return nil
+	}
+	util.Logger().Error("overwriting CA material requires confirmation", "path", path)
+	return fmt.Errorf("overwriting CA material %s invalidates every certificate it issued and requires confirmation", path)
+}
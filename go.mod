@@ -3,8 +3,10 @@ module github.com/Alonza0314/cert-go
 go 1.22.5
 
 require (
-	github.com/Alonza0314/logger-go v1.2.2
+	github.com/BurntSushi/toml v1.5.0
 	github.com/spf13/cobra v1.10.1
+	golang.org/x/sys v0.30.0
+	golang.org/x/term v0.29.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
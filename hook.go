@@ -0,0 +1,236 @@
+package certgo
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+const defaultHookTimeout = 30 * time.Second
+
+// hookHTTPClient does not follow redirects, which could lead the post away from the loopback address.
+var hookHTTPClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// HookPayload is the metadata of an event, written as JSON on the stdin of exec hooks and posted to
+// http hooks. Its fields are also available to the templates of the exec arguments, e.g. {{.CertPath}}.
+type HookPayload struct {
+	Event     constants.HookEvent `json:"event"`
+	Time      time.Time           `json:"time"`
+	Name      string              `json:"name,omitempty"`
+	Type      string              `json:"type,omitempty"`
+	CertPath  string              `json:"cert,omitempty"`
+	KeyPath   string              `json:"private_key,omitempty"`
+	CsrPath   string              `json:"csr,omitempty"`
+	KeyType   string              `json:"key_type,omitempty"`
+	Subject   string              `json:"subject,omitempty"`
+	Issuer    string              `json:"issuer,omitempty"`
+	Serial    string              `json:"serial,omitempty"`
+	NotBefore *time.Time          `json:"not_before,omitempty"`
+	NotAfter  *time.Time          `json:"not_after,omitempty"`
+	// Fingerprints are the SHA-256 of the DER of the cert, the csr and the public_key of the event, in hex.
+	Fingerprints map[string]string `json:"fingerprints,omitempty"`
+}
+
+// newHookPayload returns the payload of the event of cfg, cert and csr add their details when not nil.
+func newHookPayload(event constants.HookEvent, cfg model.Certificate, cert *x509.Certificate, csr *x509.CertificateRequest) HookPayload {
+	payload := HookPayload{
+		Event:    event,
+		Time:     util.Now(),
+		Name:     cfg.Name,
+		Type:     cfg.Type,
+		CertPath: cfg.CertFilePath,
+		KeyPath:  cfg.KeyFilePath,
+		CsrPath:  cfg.CsrFilePath,
+	}
+	if csr != nil {
+		payload.Subject = csr.Subject.String()
+		payload.Fingerprints = map[string]string{
+			"csr":        fingerprint(csr.Raw),
+			"public_key": fingerprint(csr.RawSubjectPublicKeyInfo),
+		}
+	}
+	if cert != nil {
+		payload.Subject = cert.Subject.String()
+		payload.Issuer = cert.Issuer.String()
+		payload.Serial = cert.SerialNumber.Text(16)
+		payload.NotBefore, payload.NotAfter = &cert.NotBefore, &cert.NotAfter
+		payload.Fingerprints = map[string]string{
+			"cert":       fingerprint(cert.Raw),
+			"public_key": fingerprint(cert.RawSubjectPublicKeyInfo),
+		}
+	}
+	return payload
+}
+
+// recordKeyCreated records the creation of key, the private key of cfg, and runs the hooks of cfg on it.
+func recordKeyCreated(cfg model.Certificate, key interface{}) error {
+	payload := newHookPayload(constants.HOOK_EVENT_KEY_CREATED, cfg, nil, nil)
+	payload.KeyType = string(util.GetPrivateKeyType(key))
+	if signer, ok := key.(crypto.Signer); ok {
+		if der, err := x509.MarshalPKIXPublicKey(signer.Public()); err == nil {
+			payload.Fingerprints = map[string]string{"public_key": fingerprint(der)}
+		}
+	}
+	return recordEvent(cfg.Hooks, payload)
+}
+
+// recordEvent writes the event of payload to the audit log, then runs the hooks subscribed to it.
+func recordEvent(hooks []model.Hook, payload HookPayload) error {
+	if err := writeAuditEntry(payload); err != nil {
+		return err
+	}
+	return runHooks(hooks, payload)
+}
+
+func fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// certificateHooks returns the hooks that apply to the certificate named name.
+func certificateHooks(hooks []model.Hook, name string) []model.Hook {
+	var matched []model.Hook
+	for _, hook := range hooks {
+		if len(hook.Certificates) == 0 {
+			matched = append(matched, hook)
+			continue
+		}
+		for _, c := range hook.Certificates {
+			if c == name {
+				matched = append(matched, hook)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// runHooks runs the hooks subscribed to the event of payload in order. A failing hook is logged and
+// ignored, unless its on_failure is abort: its error is returned and the following hooks are not run.
+func runHooks(hooks []model.Hook, payload HookPayload) error {
+	for i, hook := range hooks {
+		subscribed := false
+		for _, event := range hook.Events {
+			if constants.HookEvent(event) == payload.Event {
+				subscribed = true
+				break
+			}
+		}
+		if !subscribed {
+			continue
+		}
+
+		err := runHook(hook, payload)
+		if err == nil {
+			util.Logger().Debug("hook run", "hook", i+1, "event", string(payload.Event), "name", payload.Name)
+			continue
+		}
+		if constants.HookFailure(hook.OnFailure) == constants.HOOK_FAILURE_ABORT {
+			util.Logger().Error("hook failed", "hook", i+1, "event", string(payload.Event), "name", payload.Name, "error", err)
+			return fmt.Errorf("hook %d on %s: %w", i+1, payload.Event, err)
+		}
+		util.Logger().Warn("hook failed, ignore it", "hook", i+1, "event", string(payload.Event), "name", payload.Name, "error", err)
+	}
+	return nil
+}
+
+func runHook(hook model.Hook, payload HookPayload) error {
+	timeout := defaultHookTimeout
+	if hook.Timeout != "" {
+		var err error
+		if timeout, err = util.ParseDuration(hook.Timeout); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(hook.Exec) != 0:
+		args := make([]string, len(hook.Exec))
+		for i, arg := range hook.Exec {
+			if args[i], err = expandHookArg(arg, payload); err != nil {
+				return err
+			}
+		}
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stdin = bytes.NewReader(body)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+		}
+	case hook.HTTPPost != "":
+		if err := validHookURL(hook.HTTPPost); err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.HTTPPost, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := hookHTTPClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("%s: unexpected status %s", hook.HTTPPost, resp.Status)
+		}
+	default:
+		return errors.New("hook has neither exec nor http_post")
+	}
+	return nil
+}
+
+// expandHookArg executes the argument as a template of payload.
+func expandHookArg(arg string, payload HookPayload) (string, error) {
+	tmpl, err := template.New("arg").Option("missingkey=error").Parse(arg)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", arg, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, payload); err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", arg, err)
+	}
+	return b.String(), nil
+}
+
+// validHookURL checks that the url of an http hook is an http or https url of a loopback host,
+// the payload discloses the layout of the PKI.
+func validHookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid http_post url: %q, expected an http or https url", raw)
+	}
+	if ip := net.ParseIP(u.Hostname()); u.Hostname() != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("invalid http_post url: %q, the host must be localhost or a loopback address", raw)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package certgo
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks run sh")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+
+	var posted HookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &posted); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	payload := HookPayload{Event: constants.HOOK_EVENT_CERT_ISSUED, Name: "web", CertPath: "web.cert.pem"}
+	hooks := []model.Hook{
+		{Events: []string{"csr_created"}, Exec: []string{"false"}, OnFailure: "abort"},
+		{Events: []string{"cert_issued"}, Exec: []string{"sh", "-c", `cat > "$0"`, out + ".{{.Name}}"}},
+		{Events: []string{"cert_issued"}, HTTPPost: srv.URL},
+		{Events: []string{"cert_issued"}, Exec: []string{"false"}},
+	}
+	if err := runHooks(hooks, payload); err != nil {
+		t.Fatalf("TestRunHooks: %v", err)
+	}
+	data, err := os.ReadFile(out + ".web")
+	if err != nil {
+		t.Fatalf("TestRunHooks: exec hook was not run: %v", err)
+	}
+	var received HookPayload
+	if err := json.Unmarshal(data, &received); err != nil || received.CertPath != "web.cert.pem" {
+		t.Fatalf("TestRunHooks: unexpected stdin %s", data)
+	}
+	if posted.Event != constants.HOOK_EVENT_CERT_ISSUED || posted.Name != "web" {
+		t.Fatalf("TestRunHooks: unexpected post %+v", posted)
+	}
+
+	hooks[3].OnFailure = "abort"
+	if err := runHooks(hooks, payload); err == nil || !strings.HasPrefix(err.Error(), "hook 4 on cert_issued:") {
+		t.Fatalf("TestRunHooks: expected error of the aborting hook, got %v", err)
+	}
+
+	// a redirect, even to a loopback address, is not followed
+	redirected := false
+	target := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { redirected = true }))
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer redirect.Close()
+	redirecting := []model.Hook{{Events: []string{"cert_issued"}, HTTPPost: redirect.URL, OnFailure: "abort"}}
+	if err := runHooks(redirecting, payload); err == nil || redirected {
+		t.Fatalf("TestRunHooks: redirect of an http hook should not be followed, got %v", err)
+	}
+
+	remote := []model.Hook{{Events: []string{"cert_issued"}, HTTPPost: "http://example.com/hook", OnFailure: "abort"}}
+	if err := runHooks(remote, payload); err == nil {
+		t.Fatalf("TestRunHooks: expected error for a remote http hook")
+	}
+}
+
+func TestHookEvents(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks run sh")
+	}
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "cfg.yml")
+	events := filepath.Join(dir, "events.log")
+
+	cfg, err := NewStarterConfig(StarterConfig{
+		Organization: "hook",
+		OutDir:       "./ca",
+		Leaves:       []StarterLeaf{{Name: "web", Type: constants.CERT_TYPE_SERVER}},
+	})
+	if err != nil {
+		t.Fatalf("TestHookEvents: %v", err)
+	}
+	cfg.Hooks = []model.Hook{
+		{
+			Events:       []string{"key_created", "csr_created", "cert_issued", "cert_renewed"},
+			Certificates: []string{"root", "web"},
+			Exec:         []string{"sh", "-c", `echo "$0 $1" >> ` + events, "{{.Event}}", "{{.Name}}"},
+		},
+	}
+	if err := util.WriteStructToYamlFile(yamlPath, cfg); err != nil {
+		t.Fatalf("TestHookEvents: %v", err)
+	}
+
+	if _, err := InitPKI(yamlPath, ""); err != nil {
+		t.Fatalf("TestHookEvents: %v", err)
+	}
+	opts, err := RenewOptionsFromConfig(yamlPath, filepath.Join(dir, "ca", "web", "web.cert.pem"))
+	if err != nil {
+		t.Fatalf("TestHookEvents: %v", err)
+	}
+	if _, _, err := RenewCertificate(filepath.Join(dir, "ca", "web", "web.cert.pem"), opts); err != nil {
+		t.Fatalf("TestHookEvents: %v", err)
+	}
+
+	data, err := os.ReadFile(events)
+	if err != nil {
+		t.Fatalf("TestHookEvents: %v", err)
+	}
+	expect := "key_created root\ncert_issued root\nkey_created web\ncsr_created web\ncert_issued web\ncert_renewed web\n"
+	if string(data) != expect {
+		t.Fatalf("TestHookEvents: actual events\n%s!= expect\n%s", data, expect)
+	}
+
+	// an aborting hook fails the operation
+	cfg.Hooks = []model.Hook{{Events: []string{"cert_issued"}, Exec: []string{"false"}, OnFailure: "abort"}}
+	if err := util.WriteStructToYamlFile(yamlPath, cfg); err != nil {
+		t.Fatalf("TestHookEvents: %v", err)
+	}
+	if _, err := SignProfileCertificate("web", "", yamlPath, true); err == nil {
+		t.Fatalf("TestHookEvents: expected error of the aborting hook")
+	}
+}
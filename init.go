@@ -0,0 +1,252 @@
+package certgo
+
+import (
+	"crypto"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+// InitAction is what InitPKI did with a certificate.
+type InitAction string
+
+const (
+	INIT_ACTION_CREATED  InitAction = "created"
+	INIT_ACTION_REISSUED InitAction = "reissued"
+	INIT_ACTION_SKIPPED  InitAction = "skipped"
+)
+
+// InitItem reports the artifacts of one certificate handled by InitPKI.
+type InitItem struct {
+	Name       string
+	CertPath   string
+	Action     InitAction
+	Reason     string
+	KeyCreated bool
+	CsrCreated bool
+}
+
+// InitPKI signs every certificate configured in the yaml file, the root, intermediate,
+// server, client and peer slots as well as the named profiles. Certificates are signed
+// after their issuer, found by matching parent_cert with the cert path of another entry.
+// Certificates that exist, match their private key, are within their validity period and
+// are signed by their issuer are kept, the others are created or reissued.
+// New private keys are of keyType, or of the key_type of their entry when keyType is empty,
+// existing ones are reused whatever their type.
+// The items handled before an error are returned with it.
+func InitPKI(yamlPath string, keyType constants.PrivateKeyType) ([]InitItem, error) {
+	cfg, err := ReadConfig(yamlPath)
+	if err != nil {
+		return nil, err
+	}
+
+	certs, err := initOrder(cfg.CA)
+	if err != nil {
+		util.Logger().Error(err.Error(), "yaml", yamlPath)
+		return nil, err
+	}
+
+	items := make([]InitItem, 0, len(certs))
+	for _, c := range certs {
+		item, err := initCertificate(c.name, c.cfg, keyType)
+		if err != nil {
+			return items, fmt.Errorf("%s: %w", c.name, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+type initCert struct {
+	name string
+	cfg  model.Certificate
+}
+
+// initOrder returns the configured certificates with every issuer before the certificates it signs.
+func initOrder(ca model.CertificateAuthority) ([]initCert, error) {
+	names := make([]string, 0, len(ca.Profiles)+5)
+	for _, slot := range []constants.CertType{
+		constants.CERT_TYPE_ROOT,
+		constants.CERT_TYPE_INTERMEDIATE,
+		constants.CERT_TYPE_SERVER,
+		constants.CERT_TYPE_CLIENT,
+		constants.CERT_TYPE_PEER,
+	} {
+		if _, ok := ca.Profiles[string(slot)]; !ok {
+			names = append(names, string(slot))
+		}
+	}
+	profiles := make([]string, 0, len(ca.Profiles))
+	for name := range ca.Profiles {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	names = append(names, profiles...)
+
+	certs := make([]initCert, 0, len(names))
+	byPath := make(map[string]int, len(names))
+	for _, name := range names {
+		cfg, err := ResolveProfile(ca, name)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.CertFilePath == "" {
+			continue
+		}
+		path := filepath.Clean(cfg.CertFilePath)
+		if other, ok := byPath[path]; ok {
+			return nil, fmt.Errorf("profiles %s and %s share the certificate %s", certs[other].name, name, cfg.CertFilePath)
+		}
+		byPath[path] = len(certs)
+		certs = append(certs, initCert{name: name, cfg: cfg})
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make([]int, len(certs))
+	ordered := make([]initCert, 0, len(certs))
+	var visit func(i int, chain []string) error
+	visit = func(i int, chain []string) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("issuer cycle: %v", append(chain, certs[i].name))
+		}
+		state[i] = visiting
+		if parent := certs[i].cfg.ParentCertPath; parent != "" {
+			if j, ok := byPath[filepath.Clean(parent)]; ok && j != i {
+				if err := visit(j, append(chain, certs[i].name)); err != nil {
+					return err
+				}
+			}
+		}
+		state[i] = visited
+		ordered = append(ordered, certs[i])
+		return nil
+	}
+	for i := range certs {
+		if err := visit(i, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+func initCertificate(name string, cfg model.Certificate, keyType constants.PrivateKeyType) (InitItem, error) {
+	item := InitItem{Name: name, CertPath: cfg.CertFilePath}
+
+	certExists := util.FileExists(cfg.CertFilePath)
+	if certExists {
+		reason := certificateInvalidReason(cfg)
+		if reason == "" {
+			item.Action = INIT_ACTION_SKIPPED
+			util.Logger().Debug("certificate is valid, skip it", "profile", name, "path", cfg.CertFilePath)
+			return item, nil
+		}
+		item.Action, item.Reason = INIT_ACTION_REISSUED, reason
+		util.Logger().Info("certificate is not valid, reissue it", "profile", name, "path", cfg.CertFilePath, "reason", reason)
+	} else {
+		item.Action = INIT_ACTION_CREATED
+	}
+
+	// an existing private key is reused with its own type
+	if util.FileExists(cfg.KeyFilePath) {
+		key, err := util.ReadPrivateKey(cfg.KeyFilePath)
+		if err != nil {
+			return item, err
+		}
+		keyType = util.GetPrivateKeyType(key)
+	} else {
+		item.KeyCreated = true
+	}
+
+	if cfg.Type != string(constants.CERT_TYPE_ROOT) {
+		switch {
+		case !util.FileExists(cfg.CsrFilePath):
+			item.CsrCreated = true
+		case !csrMatchesKey(cfg):
+			// the csr was made for another key, create it again before signing
+			if _, err := CreateCsr(cfg, keyType, true); err != nil {
+				return item, err
+			}
+			item.CsrCreated = true
+		}
+	}
+
+	if _, err := signCertificate(cfg, keyType, certExists); err != nil {
+		return item, err
+	}
+	return item, nil
+}
+
+// certificateInvalidReason returns why the certificate of cfg cannot be kept, or an empty string if it can.
+func certificateInvalidReason(cfg model.Certificate) string {
+	cert, err := util.ReadCertificate(cfg.CertFilePath)
+	if err != nil {
+		return "unreadable certificate"
+	}
+
+	now := util.Now()
+	if now.Before(cert.NotBefore) {
+		return "not yet valid"
+	}
+	if now.After(cert.NotAfter) {
+		return "expired"
+	}
+
+	if !util.FileExists(cfg.KeyFilePath) {
+		return "missing private key"
+	}
+	key, err := util.ReadPrivateKey(cfg.KeyFilePath)
+	if err != nil {
+		return "unreadable private key"
+	}
+	if !publicKeyMatches(key, cert.PublicKey) {
+		return "private key mismatch"
+	}
+
+	issuer := cert
+	if cfg.ParentCertPath != "" {
+		if !util.FileExists(cfg.ParentCertPath) {
+			return "missing issuer certificate"
+		}
+		if issuer, err = util.ReadCertificate(cfg.ParentCertPath); err != nil {
+			return "unreadable issuer certificate"
+		}
+	}
+	if err := cert.CheckSignatureFrom(issuer); err != nil {
+		return "not signed by issuer"
+	}
+	return ""
+}
+
+func csrMatchesKey(cfg model.Certificate) bool {
+	if !util.FileExists(cfg.KeyFilePath) {
+		return false
+	}
+	csr, err := util.ReadCsr(cfg.CsrFilePath)
+	if err != nil {
+		return false
+	}
+	key, err := util.ReadPrivateKey(cfg.KeyFilePath)
+	if err != nil {
+		return false
+	}
+	return csr.CheckSignature() == nil && publicKeyMatches(key, csr.PublicKey)
+}
+
+func publicKeyMatches(privateKey interface{}, publicKey crypto.PublicKey) bool {
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return false
+	}
+	pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && pub.Equal(publicKey)
+}
@@ -0,0 +1,105 @@
+package certgo
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+func TestInitPKI(t *testing.T) {
+	yamlPath := "./defaultCfg.yml"
+	defer func() {
+		if err := os.RemoveAll("./default_ca"); err != nil {
+			t.Fatalf("TestInitPKI: %v", err)
+		}
+	}()
+
+	actions := func(items []InitItem) map[string]InitAction {
+		m := make(map[string]InitAction, len(items))
+		for _, item := range items {
+			m[item.Name] = item.Action
+		}
+		return m
+	}
+
+	items, err := InitPKI(yamlPath, constants.PRIVATE_KEY_TYPE_ECDSA)
+	if err != nil {
+		t.Fatalf("TestInitPKI: %v", err)
+	}
+	if len(items) != 6 {
+		t.Fatalf("TestInitPKI: expected 6 certificates, got %d", len(items))
+	}
+	if items[0].Name != "root" || items[1].Name != "intermediate" {
+		t.Fatalf("TestInitPKI: issuers are not signed first: %v", items)
+	}
+	for _, item := range items {
+		if item.Action != INIT_ACTION_CREATED || !item.KeyCreated {
+			t.Fatalf("TestInitPKI: %s was not created: %+v", item.Name, item)
+		}
+	}
+
+	items, err = InitPKI(yamlPath, constants.PRIVATE_KEY_TYPE_ECDSA)
+	if err != nil {
+		t.Fatalf("TestInitPKI: %v", err)
+	}
+	for _, item := range items {
+		if item.Action != INIT_ACTION_SKIPPED {
+			t.Fatalf("TestInitPKI: valid certificate %s was not skipped: %+v", item.Name, item)
+		}
+	}
+
+	// a new intermediate key invalidates every certificate it signed
+	if err := util.FileDelete("./default_ca/intermediate/intermediate.key.pem"); err != nil {
+		t.Fatalf("TestInitPKI: %v", err)
+	}
+	if _, err := InitPKI(yamlPath, constants.PRIVATE_KEY_TYPE_ECDSA); err == nil {
+		t.Fatalf("TestInitPKI: expected error for reissuing the intermediate without confirmation")
+	}
+	SetCAOverwriteConfirm(func(string) bool { return true })
+	defer SetCAOverwriteConfirm(nil)
+	items, err = InitPKI(yamlPath, constants.PRIVATE_KEY_TYPE_ECDSA)
+	if err != nil {
+		t.Fatalf("TestInitPKI: %v", err)
+	}
+	expect := map[string]InitAction{
+		"root":         INIT_ACTION_SKIPPED,
+		"intermediate": INIT_ACTION_REISSUED,
+		"server":       INIT_ACTION_REISSUED,
+		"client":       INIT_ACTION_REISSUED,
+		"peer":         INIT_ACTION_REISSUED,
+		"api-gateway":  INIT_ACTION_REISSUED,
+	}
+	for name, action := range actions(items) {
+		if expect[name] != action {
+			t.Fatalf("TestInitPKI: %s %s != %s", name, action, expect[name])
+		}
+	}
+
+	cert, err := util.ReadCertificate("./default_ca/server/server.cert.pem")
+	if err != nil {
+		t.Fatalf("TestInitPKI: %v", err)
+	}
+	intermediate, err := util.ReadCertificate("./default_ca/intermediate/intermediate.cert.pem")
+	if err != nil {
+		t.Fatalf("TestInitPKI: %v", err)
+	}
+	if err := cert.CheckSignatureFrom(intermediate); err != nil {
+		t.Fatalf("TestInitPKI: %v", err)
+	}
+}
+
+func TestInitOrderCycle(t *testing.T) {
+	ca := model.CertificateAuthority{
+		Profiles: map[string]model.Certificate{
+			"a": {CertFilePath: "a.cert.pem", ParentCertPath: "b.cert.pem"},
+			"b": {CertFilePath: "b.cert.pem", ParentCertPath: "a.cert.pem"},
+		},
+	}
+	if _, err := initOrder(ca); err == nil || !strings.Contains(err.Error(), "issuer cycle") {
+		t.Fatalf("TestInitOrderCycle: expected issuer cycle error, got %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+// Package detrand makes keys, serial numbers and signatures reproducible for tests.
+//
+// The key generation and ECDSA signing here are not constant time and derive every
+// secret from a seed, they must never be used for production keys. Only
+// certgotest.SetDeterministic and the tests of cert-go enable it.
+package detrand
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"sync/atomic"
+)
+
+type readerHolder struct{ r io.Reader }
+
+var current atomic.Pointer[readerHolder]
+
+// Enable makes cert-go draw every random byte from a reader seeded with seed, call the returned
+// function to restore crypto/rand.
+func Enable(seed int64) (restore func()) {
+	current.Store(&readerHolder{r: NewReader(seed)})
+	return func() {
+		current.Store(nil)
+	}
+}
+
+// Reader returns the seeded reader, nil when deterministic mode is not enabled.
+func Reader() io.Reader {
+	if h := current.Load(); h != nil {
+		return h.r
+	}
+	return nil
+}
+
+type reader struct {
+	seed    [8]byte
+	counter uint64
+	buf     []byte
+}
+
+// NewReader returns a reader producing the same byte stream for the same seed.
+func NewReader(seed int64) io.Reader {
+	r := &reader{}
+	binary.BigEndian.PutUint64(r.seed[:], uint64(seed))
+	return r
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.buf) == 0 {
+			var block [16]byte
+			copy(block[:8], r.seed[:])
+			binary.BigEndian.PutUint64(block[8:], r.counter)
+			r.counter++
+			sum := sha256.Sum256(block[:])
+			r.buf = sum[:]
+		}
+		c := copy(p[n:], r.buf)
+		r.buf = r.buf[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// GenerateECDSAKey generates a P-256 key whose scalar is derived from random alone,
+// crypto/ecdsa would mix in system randomness.
+func GenerateECDSAKey(random io.Reader) (*ecdsa.PrivateKey, error) {
+	curve := elliptic.P256()
+	params := curve.Params()
+	b := make([]byte, params.BitSize/8+8)
+	if _, err := io.ReadFull(random, b); err != nil {
+		return nil, err
+	}
+	d := new(big.Int).SetBytes(b)
+	d.Mod(d, new(big.Int).Sub(params.N, big.NewInt(1)))
+	d.Add(d, big.NewInt(1))
+
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: curve},
+		D:         d,
+	}
+	key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(d.FillBytes(make([]byte, params.BitSize/8)))
+	return key, nil
+}
+
+// GenerateRSAKey generates an RSA key whose primes are derived from random alone.
+func GenerateRSAKey(random io.Reader, bits int) (*rsa.PrivateKey, error) {
+	e := big.NewInt(65537)
+	one := big.NewInt(1)
+	for {
+		p, err := generatePrime(random, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		q, err := generatePrime(random, bits-bits/2)
+		if err != nil {
+			return nil, err
+		}
+		if p.Cmp(q) == 0 {
+			continue
+		}
+
+		n := new(big.Int).Mul(p, q)
+		if n.BitLen() != bits {
+			continue
+		}
+		phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+		d := new(big.Int).ModInverse(e, phi)
+		if d == nil {
+			continue
+		}
+
+		key := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: n, E: int(e.Int64())},
+			D:         d,
+			Primes:    []*big.Int{p, q},
+		}
+		key.Precompute()
+		if err := key.Validate(); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+}
+
+func generatePrime(random io.Reader, bits int) (*big.Int, error) {
+	b := make([]byte, (bits+7)/8)
+	if _, err := io.ReadFull(random, b); err != nil {
+		return nil, err
+	}
+
+	// keep the top two bits set so the product of two primes has the full length
+	extra := uint(len(b)*8 - bits)
+	b[0] &= uint8(int(1<<(8-extra)) - 1)
+	if extra < 7 {
+		b[0] |= 0xc0 >> extra
+	} else {
+		b[0] |= 0x01
+		b[1] |= 0x80
+	}
+	b[len(b)-1] |= 1
+
+	p := new(big.Int).SetBytes(b)
+	for !p.ProbablyPrime(20) {
+		p.Add(p, big.NewInt(2))
+	}
+	return p, nil
+}
+
+// Signer wraps ECDSA keys to sign with a nonce derived from random, the key and the digest,
+// crypto/ecdsa always mixes system randomness into signatures. Other keys are returned as is.
+func Signer(key interface{}, random io.Reader) interface{} {
+	if ecdsaKey, ok := key.(*ecdsa.PrivateKey); ok {
+		return &ecdsaSigner{key: ecdsaKey, random: random}
+	}
+	return key
+}
+
+type ecdsaSigner struct {
+	key    *ecdsa.PrivateKey
+	random io.Reader
+}
+
+func (s *ecdsaSigner) Public() crypto.PublicKey {
+	return &s.key.PublicKey
+}
+
+func (s *ecdsaSigner) Sign(_ io.Reader, digest []byte, _ crypto.SignerOpts) ([]byte, error) {
+	params := s.key.Curve.Params()
+	n := params.N
+	e := hashToInt(digest, n)
+
+	entropy := make([]byte, 32)
+	for {
+		if _, err := io.ReadFull(s.random, entropy); err != nil {
+			return nil, err
+		}
+		h := sha256.New()
+		h.Write(entropy)
+		h.Write(s.key.D.Bytes())
+		h.Write(digest)
+		k := new(big.Int).SetBytes(h.Sum(nil))
+		k.Mod(k, new(big.Int).Sub(n, big.NewInt(1)))
+		k.Add(k, big.NewInt(1))
+
+		x, _ := s.key.Curve.ScalarBaseMult(k.Bytes())
+		r := new(big.Int).Mod(x, n)
+		if r.Sign() == 0 {
+			continue
+		}
+
+		kInv := new(big.Int).ModInverse(k, n)
+		sig := new(big.Int).Mul(r, s.key.D)
+		sig.Add(sig, e)
+		sig.Mul(sig, kInv)
+		sig.Mod(sig, n)
+		if sig.Sign() == 0 {
+			continue
+		}
+
+		return asn1.Marshal(struct{ R, S *big.Int }{r, sig})
+	}
+}
+
+func hashToInt(digest []byte, n *big.Int) *big.Int {
+	orderBits := n.BitLen()
+	orderBytes := (orderBits + 7) / 8
+	if len(digest) > orderBytes {
+		digest = digest[:orderBytes]
+	}
+	e := new(big.Int).SetBytes(digest)
+	if excess := len(digest)*8 - orderBits; excess > 0 {
+		e.Rsh(e, uint(excess))
+	}
+	return e
+}
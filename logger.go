@@ -0,0 +1,13 @@
+package certgo
+
+import (
+	"log/slog"
+
+	"github.com/Alonza0314/cert-go/util"
+)
+
+// SetLogger sets the logger used by cert-go. By default nothing is logged,
+// pass nil to restore the default.
+func SetLogger(l *slog.Logger) {
+	util.SetLogger(l)
+}
@@ -0,0 +1,51 @@
+package certgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+func TestSetLogger(t *testing.T) {
+	keyPath := "./default_ca/test.key.pem"
+
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	defer SetLogger(nil)
+
+	if _, err := CreatePrivateKey(keyPath, constants.PRIVATE_KEY_TYPE_ECDSA, false); err != nil {
+		t.Fatalf("TestSetLogger: %v", err)
+	}
+	defer func() {
+		if err := util.FileDelete(keyPath); err != nil {
+			t.Fatalf("TestSetLogger: failed to delete key: %v", err)
+		}
+	}()
+
+	found := false
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var record map[string]interface{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("TestSetLogger: %v", err)
+		}
+		if record["msg"] == "private key created" && record["path"] == keyPath {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("TestSetLogger: private key created record not found in %s", buf.String())
+	}
+
+	SetLogger(nil)
+	buf.Reset()
+	if _, err := CreatePrivateKey(keyPath, constants.PRIVATE_KEY_TYPE_ECDSA, true); err != nil {
+		t.Fatalf("TestSetLogger: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("TestSetLogger: default logger should discard records")
+	}
+}
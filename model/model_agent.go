@@ -0,0 +1,19 @@
+package model
+
+// Agent configures the agent that renews the certificates of the configuration in the background.
+type Agent struct {
+	// Interval is the time between two checks, Jitter a random delay added to each one.
+	Interval string `yaml:"interval,omitempty"`
+	Jitter   string `yaml:"jitter,omitempty"`
+	// RenewBefore renews a certificate when it expires within this duration.
+	RenewBefore string      `yaml:"renew_before,omitempty"`
+	HealthAddr  string      `yaml:"health_addr,omitempty"`
+	Hooks       []AgentHook `yaml:"hooks,omitempty"`
+}
+
+// AgentHook is run once after a check that renewed any of its certificates, all of them when Certificates is empty.
+type AgentHook struct {
+	Certificates  []string `yaml:"certificates,omitempty"`
+	Exec          []string `yaml:"exec,omitempty"`
+	SignalPidFile string   `yaml:"signal_pid_file,omitempty"`
+}
@@ -1,5 +1,9 @@
 package model
 
 type CAConfig struct {
-	CA CertificateAuthority `yaml:"ca"`
+	// RelativeToCwd resolves relative paths against the working directory instead of the directory of the yaml file.
+	RelativeToCwd bool                 `yaml:"relative_to_cwd,omitempty"`
+	CA            CertificateAuthority `yaml:"ca"`
+	Hooks         []Hook               `yaml:"hooks,omitempty"`
+	Agent         Agent                `yaml:"agent,omitempty"`
 }
@@ -2,29 +2,49 @@ package model
 
 import (
 	"crypto/x509"
+	"encoding/asn1"
 )
 
 type Certificate struct {
-	Type         string `yaml:"type"`
+	Type         string `yaml:"type,omitempty"`
 	CertFilePath string `yaml:"cert"`
 	KeyFilePath  string `yaml:"private_key"`
-	CsrFilePath  string `yaml:"csr"`
+	CsrFilePath  string `yaml:"csr,omitempty"`
+	KeyType      string `yaml:"key_type,omitempty"`
 
-	ParentCertPath string `yaml:"parent_cert"`
-	ParentKeyPath  string `yaml:"parent_key"`
-	ParentCert     *x509.Certificate
-	ParentKey      interface{}
+	Issuer         string            `yaml:"issuer,omitempty"`
+	ParentCertPath string            `yaml:"parent_cert,omitempty"`
+	ParentKeyPath  string            `yaml:"parent_key,omitempty"`
+	ParentCert     *x509.Certificate `yaml:"-"`
+	ParentKey      interface{}       `yaml:"-"`
 
-	IsCA          bool   `yaml:"is_ca"`
-	Organization  string `yaml:"organization"`
-	CommonName    string `yaml:"common_name"`
-	ValidityYears int    `yaml:"validity_years"`
-	ValidityMonth int    `yaml:"validity_month"`
-	ValidityDay   int    `yaml:"validity_day"`
-	KeyUsage      x509.KeyUsage
-	ExtKeyUsage   []x509.ExtKeyUsage
+	IsCA               bool                    `yaml:"is_ca"`
+	Organization       string                  `yaml:"organization"`
+	CommonName         string                  `yaml:"common_name"`
+	ValidityYears      int                     `yaml:"validity_years"`
+	ValidityMonth      int                     `yaml:"validity_month"`
+	ValidityDay        int                     `yaml:"validity_day"`
+	Validity           string                  `yaml:"validity,omitempty"`
+	NotBefore          string                  `yaml:"not_before,omitempty"`
+	NotAfter           string                  `yaml:"not_after,omitempty"`
+	Backdate           string                  `yaml:"backdate,omitempty"`
+	StrictValidity     bool                    `yaml:"strict_validity,omitempty"`
+	KeyUsageNames      []string                `yaml:"key_usage,omitempty"`
+	ExtKeyUsageNames   []string                `yaml:"ext_key_usage,omitempty"`
+	KeyUsage           x509.KeyUsage           `yaml:"-"`
+	ExtKeyUsage        []x509.ExtKeyUsage      `yaml:"-"`
+	UnknownExtKeyUsage []asn1.ObjectIdentifier `yaml:"-"`
 
-	DNSNames    []string `yaml:"dns_names"`
-	IPAddresses []string `yaml:"ip_addresses"`
-	URIs        []string `yaml:"uris"`
+	DNSNames    []string `yaml:"dns_names,omitempty"`
+	IPAddresses []string `yaml:"ip_addresses,omitempty"`
+	URIs        []string `yaml:"uris,omitempty"`
+
+	// Policy constrains the certificates signed by this certificate, it is only valid on CAs.
+	Policy *Policy `yaml:"policy,omitempty"`
+
+	// Name, Hooks and IssuerPolicy are set when the certificate is read from a configuration, Hooks
+	// are the ones of the configuration that apply to it and IssuerPolicy is the policy of its issuer.
+	Name         string  `yaml:"-"`
+	Hooks        []Hook  `yaml:"-"`
+	IssuerPolicy *Policy `yaml:"-"`
 }
@@ -1,8 +1,31 @@
 package model
 
 type CertificateAuthority struct {
-	Root         Certificate `yaml:"root"`
-	Intermediate Certificate `yaml:"intermediate"`
-	Server       Certificate `yaml:"server"`
-	Client       Certificate `yaml:"client"`
+	Root         Certificate            `yaml:"root,omitempty"`
+	Intermediate Certificate            `yaml:"intermediate,omitempty"`
+	Server       Certificate            `yaml:"server,omitempty"`
+	Client       Certificate            `yaml:"client,omitempty"`
+	Peer         Certificate            `yaml:"peer,omitempty"`
+	Profiles     map[string]Certificate `yaml:"profiles,omitempty"`
+}
+
+// Profile returns the certificate named name. Named profiles take precedence
+// over the root, intermediate, server, client and peer slots.
+func (ca CertificateAuthority) Profile(name string) (Certificate, bool) {
+	if cfg, ok := ca.Profiles[name]; ok {
+		return cfg, true
+	}
+	switch name {
+	case "root":
+		return ca.Root, true
+	case "intermediate":
+		return ca.Intermediate, true
+	case "server":
+		return ca.Server, true
+	case "client":
+		return ca.Client, true
+	case "peer":
+		return ca.Peer, true
+	}
+	return Certificate{}, false
 }
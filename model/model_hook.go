@@ -0,0 +1,13 @@
+package model
+
+// Hook is run on the events it lists, for the certificates it lists or all of them when Certificates is empty.
+// It runs Exec, whose arguments are templates of the event, or posts the event to HTTPPost.
+type Hook struct {
+	Events       []string `yaml:"events"`
+	Certificates []string `yaml:"certificates,omitempty"`
+	Exec         []string `yaml:"exec,omitempty"`
+	HTTPPost     string   `yaml:"http_post,omitempty"`
+	Timeout      string   `yaml:"timeout,omitempty"`
+	// OnFailure is ignore, the default, or abort to fail the operation that triggered the hook.
+	OnFailure string `yaml:"on_failure,omitempty"`
+}
@@ -0,0 +1,25 @@
+package model
+
+// Policy constrains the certificates signed by the CA it is set on, an empty field allows anything.
+// Once one of DNSSuffixes, IPRanges, URIPrefixes or EmailDomains is set, only the names they list are
+// allowed, and the common name of a leaf must be a listed dns name or ip address.
+type Policy struct {
+	// DNSSuffixes are the domains the dns names must be equal to or under, e.g. example.com.
+	DNSSuffixes []string `yaml:"dns_suffixes,omitempty"`
+	// IPRanges are the CIDRs the ip addresses must be in, e.g. 10.0.0.0/8.
+	IPRanges []string `yaml:"ip_ranges,omitempty"`
+	// URIPrefixes are the scheme, host and path prefix the uris must have, e.g. spiffe://example.com/ns/prod.
+	URIPrefixes []string `yaml:"uri_prefixes,omitempty"`
+	// EmailDomains are the domains the email addresses must be equal to or under, e.g. example.com.
+	EmailDomains []string `yaml:"email_domains,omitempty"`
+	// MaxValidity is the longest validity, a number of days like 90d or a duration like 2160h.
+	MaxValidity string `yaml:"max_validity,omitempty"`
+	// KeyAlgorithms are the allowed key types, ecdsa or rsa.
+	KeyAlgorithms []string `yaml:"key_algorithms,omitempty"`
+	// MinKeyBits is the minimum size of the keys per key type, e.g. rsa: 3072.
+	MinKeyBits map[string]int `yaml:"min_key_bits,omitempty"`
+	// RequiredSubject are the subject fields that must be set, e.g. common_name or organization.
+	RequiredSubject []string `yaml:"required_subject,omitempty"`
+	// ExtKeyUsage are the allowed extended key usages, names or dotted object identifiers.
+	ExtKeyUsage []string `yaml:"ext_key_usage,omitempty"`
+}
@@ -0,0 +1,262 @@
+package certgo
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+// policySubjectFields are the subject fields a policy can require.
+var policySubjectFields = map[string]func(pkix.Name) bool{
+	"common_name":         func(n pkix.Name) bool { return n.CommonName != "" },
+	"serial_number":       func(n pkix.Name) bool { return n.SerialNumber != "" },
+	"organization":        func(n pkix.Name) bool { return len(n.Organization) != 0 },
+	"organizational_unit": func(n pkix.Name) bool { return len(n.OrganizationalUnit) != 0 },
+	"country":             func(n pkix.Name) bool { return len(n.Country) != 0 },
+	"province":            func(n pkix.Name) bool { return len(n.Province) != 0 },
+	"locality":            func(n pkix.Name) bool { return len(n.Locality) != 0 },
+	"street_address":      func(n pkix.Name) bool { return len(n.StreetAddress) != 0 },
+	"postal_code":         func(n pkix.Name) bool { return len(n.PostalCode) != 0 },
+}
+
+// validatePolicy returns the problems of policy, one message per invalid value.
+func validatePolicy(policy model.Policy) []string {
+	var problems []string
+	for _, suffix := range policy.DNSSuffixes {
+		if !validDNSName(strings.TrimPrefix(suffix, ".")) || strings.HasPrefix(suffix, "*.") {
+			problems = append(problems, fmt.Sprintf("invalid dns suffix %q", suffix))
+		}
+	}
+	for _, ipRange := range policy.IPRanges {
+		if _, _, err := net.ParseCIDR(ipRange); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid ip range %q, expected a CIDR like 10.0.0.0/8", ipRange))
+		}
+	}
+	for _, prefix := range policy.URIPrefixes {
+		if u, err := url.Parse(prefix); err != nil || u.Scheme == "" || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+			problems = append(problems, fmt.Sprintf("invalid uri prefix %q, expected a scheme, a host and an optional path like spiffe://example.com/ns/prod", prefix))
+		}
+	}
+	for _, domain := range policy.EmailDomains {
+		if !validDNSName(strings.TrimPrefix(domain, ".")) || strings.HasPrefix(domain, "*.") {
+			problems = append(problems, fmt.Sprintf("invalid email domain %q", domain))
+		}
+	}
+	if policy.MaxValidity != "" {
+		if d, err := util.ParseDayDuration(policy.MaxValidity); err != nil || d == 0 {
+			problems = append(problems, fmt.Sprintf("invalid max_validity %q, expected a number of days like 90d or a duration like 2160h", policy.MaxValidity))
+		}
+	}
+	for _, algorithm := range policy.KeyAlgorithms {
+		if _, err := util.ParsePrivateKeyType(algorithm); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid key algorithm %q, expected ecdsa or rsa", algorithm))
+		}
+	}
+	algorithms := make([]string, 0, len(policy.MinKeyBits))
+	for algorithm := range policy.MinKeyBits {
+		algorithms = append(algorithms, algorithm)
+	}
+	sort.Strings(algorithms)
+	for _, algorithm := range algorithms {
+		bits := policy.MinKeyBits[algorithm]
+		if _, err := util.ParsePrivateKeyType(algorithm); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid key algorithm %q in min_key_bits, expected ecdsa or rsa", algorithm))
+		} else if bits < 0 {
+			problems = append(problems, fmt.Sprintf("min_key_bits of %s cannot be negative", algorithm))
+		}
+	}
+	for _, field := range policy.RequiredSubject {
+		if _, ok := policySubjectFields[field]; !ok {
+			problems = append(problems, fmt.Sprintf("unknown subject field %q", field))
+		}
+	}
+	if _, _, err := util.ParseExtKeyUsage(policy.ExtKeyUsage); err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems
+}
+
+// checkPolicy returns an error listing every violation of policy by template for publicKey, nothing is
+// checked when policy is nil. path is only logged.
+func checkPolicy(policy *model.Policy, template *x509.Certificate, publicKey interface{}, path string) error {
+	if policy == nil {
+		return nil
+	}
+	if problems := validatePolicy(*policy); len(problems) != 0 {
+		util.Logger().Error("invalid policy of the issuer", "path", path, "problems", strings.Join(problems, "; "))
+		return fmt.Errorf("invalid policy of the issuer: %s", strings.Join(problems, "; "))
+	}
+
+	var violations []string
+	if constrainsNames(*policy) {
+		violations = append(violations, nameViolations(*policy, template)...)
+	}
+	if policy.MaxValidity != "" {
+		maxValidity, _ := util.ParseDayDuration(policy.MaxValidity)
+		if validity := template.NotAfter.Sub(template.NotBefore); validity > maxValidity {
+			violations = append(violations, fmt.Sprintf("validity of %s exceeds max_validity %s", validity, policy.MaxValidity))
+		}
+	}
+
+	algorithm, bits := publicKeyAlgorithm(publicKey)
+	if len(policy.KeyAlgorithms) != 0 && !contains(policy.KeyAlgorithms, algorithm) {
+		violations = append(violations, fmt.Sprintf("key algorithm %s is not one of %s", algorithm, strings.Join(policy.KeyAlgorithms, ", ")))
+	}
+	if minBits := policy.MinKeyBits[algorithm]; bits < minBits {
+		violations = append(violations, fmt.Sprintf("%s key of %d bits is smaller than %d bits", algorithm, bits, minBits))
+	}
+
+	for _, field := range policy.RequiredSubject {
+		if !policySubjectFields[field](template.Subject) {
+			violations = append(violations, fmt.Sprintf("subject field %s is required", field))
+		}
+	}
+
+	if len(policy.ExtKeyUsage) != 0 {
+		allowed, allowedOIDs, _ := util.ParseExtKeyUsage(policy.ExtKeyUsage)
+		for _, usage := range template.ExtKeyUsage {
+			found := false
+			for _, a := range allowed {
+				found = found || a == usage
+			}
+			if !found {
+				violations = append(violations, fmt.Sprintf("extended key usage %s is not allowed", util.ExtKeyUsageName(usage)))
+			}
+		}
+		for _, oid := range template.UnknownExtKeyUsage {
+			found := false
+			for _, a := range allowedOIDs {
+				found = found || a.Equal(oid)
+			}
+			if !found {
+				violations = append(violations, fmt.Sprintf("extended key usage %s is not allowed", oid))
+			}
+		}
+	}
+
+	if len(violations) != 0 {
+		util.Logger().Error("certificate rejected by the policy of the issuer", "path", path, "violations", strings.Join(violations, "; "))
+		return fmt.Errorf("rejected by the policy of the issuer: %s", strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// constrainsNames reports whether policy lists the allowed names.
+func constrainsNames(policy model.Policy) bool {
+	return len(policy.DNSSuffixes) != 0 || len(policy.IPRanges) != 0 || len(policy.URIPrefixes) != 0 || len(policy.EmailDomains) != 0
+}
+
+// nameViolations returns the names of template policy does not list, names of a type without list
+// included. The common name of a leaf is checked as a dns name, or as an ip address when it is one.
+func nameViolations(policy model.Policy, template *x509.Certificate) []string {
+	var violations []string
+	notAllowed := func(kind, name, relation, field string, list []string) {
+		if len(list) == 0 {
+			violations = append(violations, fmt.Sprintf("%s %s is not allowed, the policy has no %s", kind, name, field))
+			return
+		}
+		violations = append(violations, fmt.Sprintf("%s %s is not %s %s", kind, name, relation, strings.Join(list, ", ")))
+	}
+
+	if cn := template.Subject.CommonName; cn != "" && !template.IsCA {
+		if ip := net.ParseIP(cn); ip != nil {
+			if !ipInRanges(ip, policy.IPRanges) {
+				notAllowed("common name", strconv.Quote(cn), "in", "ip_ranges", policy.IPRanges)
+			}
+		} else if !dnsNameUnder(cn, policy.DNSSuffixes) {
+			notAllowed("common name", strconv.Quote(cn), "under", "dns_suffixes", policy.DNSSuffixes)
+		}
+	}
+	for _, name := range template.DNSNames {
+		if !dnsNameUnder(name, policy.DNSSuffixes) {
+			notAllowed("dns name", strconv.Quote(name), "under", "dns_suffixes", policy.DNSSuffixes)
+		}
+	}
+	for _, ip := range template.IPAddresses {
+		if !ipInRanges(ip, policy.IPRanges) {
+			notAllowed("ip address", ip.String(), "in", "ip_ranges", policy.IPRanges)
+		}
+	}
+	for _, uri := range template.URIs {
+		if !uriUnder(uri, policy.URIPrefixes) {
+			notAllowed("uri", strconv.Quote(uri.String()), "under", "uri_prefixes", policy.URIPrefixes)
+		}
+	}
+	for _, email := range template.EmailAddresses {
+		at := strings.LastIndex(email, "@")
+		if at < 0 || !dnsNameUnder(email[at+1:], policy.EmailDomains) {
+			notAllowed("email address", strconv.Quote(email), "under", "email_domains", policy.EmailDomains)
+		}
+	}
+	return violations
+}
+
+// uriUnder reports whether uri has the scheme and host of one of prefixes and its path is equal to
+// or under the path of the prefix.
+func uriUnder(uri *url.URL, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		p, err := url.Parse(prefix)
+		if err != nil || !strings.EqualFold(uri.Scheme, p.Scheme) || !strings.EqualFold(uri.Host, p.Host) || uri.User != nil {
+			continue
+		}
+		path := strings.TrimSuffix(p.Path, "/")
+		if path == "" || uri.Path == path || strings.HasPrefix(uri.Path, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// dnsNameUnder reports whether name, or the domain of a wildcard name, is equal to or under one of suffixes.
+func dnsNameUnder(name string, suffixes []string) bool {
+	name = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(name, "*."), "."))
+	for _, suffix := range suffixes {
+		suffix = strings.ToLower(strings.TrimPrefix(suffix, "."))
+		if name == suffix || strings.HasSuffix(name, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func ipInRanges(ip net.IP, ranges []string) bool {
+	for _, r := range ranges {
+		if _, ipNet, err := net.ParseCIDR(r); err == nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// publicKeyAlgorithm returns the key type name, as in key_type, and the size in bits of publicKey.
+func publicKeyAlgorithm(publicKey interface{}) (string, int) {
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return "ecdsa", key.Curve.Params().BitSize
+	case *rsa.PublicKey:
+		return "rsa", key.N.BitLen()
+	case ed25519.PublicKey:
+		return "ed25519", 256
+	}
+	return "unknown", 0
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,227 @@
+package certgo
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+var testPolicy = model.Policy{
+	DNSSuffixes:     []string{"example.com"},
+	IPRanges:        []string{"10.0.0.0/8"},
+	MaxValidity:     "90d",
+	KeyAlgorithms:   []string{"ecdsa", "rsa"},
+	MinKeyBits:      map[string]int{"rsa": 3072},
+	RequiredSubject: []string{"common_name", "organization"},
+	ExtKeyUsage:     []string{"serverAuth"},
+}
+
+var testNamePolicy = model.Policy{
+	DNSSuffixes:  []string{"example.com"},
+	IPRanges:     []string{"10.0.0.0/8"},
+	URIPrefixes:  []string{"spiffe://example.com/ns/prod"},
+	EmailDomains: []string{"example.com"},
+}
+
+var testCaseCheckPolicy = []struct {
+	name   string
+	policy *model.Policy
+	modify func(template *x509.Certificate)
+	rsa    bool
+	expect string
+}{
+	{
+		name:   "allowed",
+		modify: func(template *x509.Certificate) {},
+	},
+	{
+		name: "dns name outside the suffixes",
+		modify: func(template *x509.Certificate) {
+			template.DNSNames = append(template.DNSNames, "api.example.com.evil.test")
+		},
+		expect: `dns name "api.example.com.evil.test" is not under example.com`,
+	},
+	{
+		name:   "ip address outside the ranges",
+		modify: func(template *x509.Certificate) { template.IPAddresses = []net.IP{net.ParseIP("192.168.1.1")} },
+		expect: "ip address 192.168.1.1 is not in 10.0.0.0/8",
+	},
+	{
+		name: "uri without uri_prefixes",
+		modify: func(template *x509.Certificate) {
+			template.URIs = []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/ns/prod"}}
+		},
+		expect: `uri "spiffe://example.com/ns/prod" is not allowed, the policy has no uri_prefixes`,
+	},
+	{
+		name:   "email address without email_domains",
+		modify: func(template *x509.Certificate) { template.EmailAddresses = []string{"admin@example.com"} },
+		expect: `email address "admin@example.com" is not allowed, the policy has no email_domains`,
+	},
+	{
+		name:   "common name outside the suffixes",
+		modify: func(template *x509.Certificate) { template.Subject.CommonName = "www.other.test" },
+		expect: `common name "www.other.test" is not under example.com`,
+	},
+	{
+		name:   "common name ip outside the ranges",
+		modify: func(template *x509.Certificate) { template.Subject.CommonName = "192.168.1.1" },
+		expect: `common name "192.168.1.1" is not in 10.0.0.0/8`,
+	},
+	{
+		name: "common name of a CA",
+		modify: func(template *x509.Certificate) {
+			template.IsCA = true
+			template.Subject.CommonName = "acme Intermediate CA"
+		},
+	},
+	{
+		name:   "uri and email address under the policy",
+		policy: &testNamePolicy,
+		modify: func(template *x509.Certificate) {
+			template.URIs = []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/ns/prod/sa/api"}}
+			template.EmailAddresses = []string{"admin@mail.example.com"}
+		},
+	},
+	{
+		name:   "uri outside the prefixes",
+		policy: &testNamePolicy,
+		modify: func(template *x509.Certificate) {
+			template.URIs = []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/ns/production"}}
+		},
+		expect: `uri "spiffe://example.com/ns/production" is not under spiffe://example.com/ns/prod`,
+	},
+	{
+		name:   "email address outside the domains",
+		policy: &testNamePolicy,
+		modify: func(template *x509.Certificate) { template.EmailAddresses = []string{"admin@example.com.evil.test"} },
+		expect: `email address "admin@example.com.evil.test" is not under example.com`,
+	},
+	{
+		name:   "validity too long",
+		modify: func(template *x509.Certificate) { template.NotAfter = template.NotBefore.AddDate(1, 0, 0) },
+		expect: "validity of 8760h0m0s exceeds max_validity 90d",
+	},
+	{
+		name:   "small rsa key",
+		modify: func(template *x509.Certificate) {},
+		rsa:    true,
+		expect: "rsa key of 2048 bits is smaller than 3072 bits",
+	},
+	{
+		name:   "missing subject field",
+		modify: func(template *x509.Certificate) { template.Subject.Organization = nil },
+		expect: "subject field organization is required",
+	},
+	{
+		name: "extended key usage not allowed",
+		modify: func(template *x509.Certificate) {
+			template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		},
+		expect: "extended key usage clientAuth is not allowed",
+	},
+}
+
+func TestCheckPolicy(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("TestCheckPolicy: %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("TestCheckPolicy: %v", err)
+	}
+
+	for _, testCase := range testCaseCheckPolicy {
+		t.Run(testCase.name, func(t *testing.T) {
+			now := time.Now()
+			template := &x509.Certificate{
+				Subject:     pkix.Name{CommonName: "api.example.com", Organization: []string{"acme"}},
+				DNSNames:    []string{"api.example.com", "*.api.example.com", "example.com"},
+				IPAddresses: []net.IP{net.ParseIP("10.1.2.3")},
+				NotBefore:   now,
+				NotAfter:    now.AddDate(0, 0, 90),
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			}
+			testCase.modify(template)
+			var publicKey interface{} = &ecdsaKey.PublicKey
+			if testCase.rsa {
+				publicKey = &rsaKey.PublicKey
+			}
+
+			policy := testCase.policy
+			if policy == nil {
+				policy = &testPolicy
+			}
+			err := checkPolicy(policy, template, publicKey, "")
+			if testCase.expect == "" {
+				if err != nil {
+					t.Fatalf("TestCheckPolicy (%s): %v", testCase.name, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), testCase.expect) {
+				t.Fatalf("TestCheckPolicy (%s): actual error %v, expect %s", testCase.name, err, testCase.expect)
+			}
+		})
+	}
+}
+
+func TestIssuerPolicy(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "cfg.yml")
+
+	cfg, err := NewStarterConfig(StarterConfig{
+		Organization: "policy",
+		OutDir:       "./ca",
+		DNSNames:     []string{"api.example.com"},
+		Leaves:       []StarterLeaf{{Name: "web", Type: constants.CERT_TYPE_SERVER}},
+	})
+	if err != nil {
+		t.Fatalf("TestIssuerPolicy: %v", err)
+	}
+	cfg.CA.Intermediate.Policy = &model.Policy{DNSSuffixes: []string{"example.com"}, MaxValidity: "400d"}
+	if err := util.WriteStructToYamlFile(yamlPath, cfg); err != nil {
+		t.Fatalf("TestIssuerPolicy: %v", err)
+	}
+	if _, err := InitPKI(yamlPath, ""); err != nil {
+		t.Fatalf("TestIssuerPolicy: %v", err)
+	}
+
+	web := cfg.CA.Profiles["web"]
+	web.DNSNames = append(web.DNSNames, "www.other.test")
+	cfg.CA.Profiles["web"] = web
+	if err := util.WriteStructToYamlFile(yamlPath, cfg); err != nil {
+		t.Fatalf("TestIssuerPolicy: %v", err)
+	}
+	_, err = SignProfileCertificate("web", "", yamlPath, true)
+	if err == nil || !strings.Contains(err.Error(), `dns name "www.other.test" is not under example.com`) {
+		t.Fatalf("TestIssuerPolicy: expected rejection by the policy of the intermediate, got %v", err)
+	}
+
+	// renewals are checked against the policy too
+	opts, err := RenewOptionsFromConfig(yamlPath, filepath.Join(dir, "ca", "web", "web.cert.pem"))
+	if err != nil {
+		t.Fatalf("TestIssuerPolicy: %v", err)
+	}
+	if opts.Policy == nil {
+		t.Fatalf("TestIssuerPolicy: renewal options without the policy of the issuer")
+	}
+	opts.Validity = 500 * 24 * time.Hour
+	if _, _, err := RenewCertificate(filepath.Join(dir, "ca", "web", "web.cert.pem"), opts); err == nil || !strings.Contains(err.Error(), "exceeds max_validity 400d") {
+		t.Fatalf("TestIssuerPolicy: expected rejection of the renewal, got %v", err)
+	}
+}
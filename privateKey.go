@@ -3,88 +3,156 @@ package certgo
 import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/rand"
 	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/internal/detrand"
+	"github.com/Alonza0314/cert-go/model"
 	"github.com/Alonza0314/cert-go/util"
-	logger "github.com/Alonza0314/logger-go"
 )
 
 func CreatePrivateKey(keyPath string, keyType constants.PrivateKeyType, overwrite bool) (interface{}, error) {
-	logger.Info("CreatePrivateKey", "creating private key")
+	privateKey, err := createPrivateKey(keyPath, keyType, overwrite)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordKeyCreated(model.Certificate{KeyFilePath: keyPath}, privateKey); err != nil {
+		return nil, err
+	}
+	return privateKey, nil
+}
+
+// createPrivateKey creates the private key of CreatePrivateKey, its creation is recorded by the caller.
+func createPrivateKey(keyPath string, keyType constants.PrivateKeyType, overwrite bool) (interface{}, error) {
+	util.Logger().Debug("creating private key", "path", keyPath, "key_type", string(keyType))
 
 	// check if private key exists
 	if util.FileExists(keyPath) {
 		if !overwrite {
-			logger.Error("CreatePrivateKey", fmt.Sprintf("private key already exists at %s.", keyPath))
+			util.Logger().Error("private key already exists", "path", keyPath)
 			return nil, errors.New("private key already exists")
 		}
-		logger.Warn("CreatePrivateKey", "private key already exists. Overwrite it")
-		if err := util.FileDelete(keyPath); err != nil {
-			logger.Error("CreatePrivateKey", "failed to remove existing private key: "+err.Error())
-			return nil, err
+		if certPath := caCertificateOfKey(keyPath); certPath != "" {
+			util.Logger().Warn("private key belongs to a CA certificate", "path", keyPath, "cert", certPath)
+			if err := confirmCAOverwrite(keyPath); err != nil {
+				return nil, err
+			}
 		}
+		// replaced only once the new private key is written
+		util.Logger().Warn("private key already exists, overwrite it", "path", keyPath)
 	}
 
-	var privateKey interface{}
-	var keyBytes []byte
+	privateKey, err := GeneratePrivateKey(keyType)
+	if err != nil {
+		return nil, err
+	}
 
-	// generate private key
-	switch keyType {
-	case constants.PRIVATE_KEY_TYPE_ECDSA:
-		logger.Info("CreatePrivateKey", "generating ECDSA private key")
-		ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keyPEM, err := util.EncodePrivateKeyPEM(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// save private key, its directory is created private
+	if err := util.WritePrivateKeyFile(keyPath, keyPEM); err != nil {
+		return nil, err
+	}
+
+	util.Logger().Info("private key created", "path", keyPath, "key_type", string(keyType))
+	return privateKey, nil
+}
+
+// caCertificateOfKey returns the path of a CA certificate in the directory of keyPath whose public key
+// is the one of the private key at keyPath, or an empty string if there is none.
+func caCertificateOfKey(keyPath string) string {
+	key, err := util.ReadPrivateKey(keyPath)
+	if err != nil {
+		return ""
+	}
+	dir := filepath.Dir(keyPath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if !entry.Type().IsRegular() || filepath.Ext(path) == util.BackupExtension || path == filepath.Clean(keyPath) {
+			continue
+		}
+		data, err := os.ReadFile(path)
 		if err != nil {
-			logger.Error("CreatePrivateKey", err.Error())
-			return nil, err
+			continue
+		}
+		certs, err := util.ParseCertificates(data, derExtensions[strings.ToLower(filepath.Ext(path))])
+		if err != nil {
+			continue
+		}
+		for _, cert := range certs {
+			if cert.IsCA && publicKeyMatches(key, cert.PublicKey) {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// certificateKeyType returns the type of the private key of cfg: keyType when it is set,
+// else the key_type of cfg, else ECDSA. An unknown key_type is rejected in any case.
+func certificateKeyType(cfg model.Certificate, keyType constants.PrivateKeyType) (constants.PrivateKeyType, error) {
+	configured := constants.PRIVATE_KEY_TYPE_ECDSA
+	if cfg.KeyType != "" {
+		var err error
+		if configured, err = util.ParsePrivateKeyType(cfg.KeyType); err != nil {
+			util.Logger().Error(err.Error(), "path", cfg.KeyFilePath)
+			return "", err
 		}
-		privateKey = ecdsaKey
+	}
+	if keyType != "" {
+		return keyType, nil
+	}
+	return configured, nil
+}
 
-		keyBytes, err = x509.MarshalECPrivateKey(ecdsaKey)
+// GeneratePrivateKey generates a private key in memory, nothing is written to disk.
+func GeneratePrivateKey(keyType constants.PrivateKeyType) (interface{}, error) {
+	switch keyType {
+	case constants.PRIVATE_KEY_TYPE_ECDSA:
+		util.Logger().Debug("generating ECDSA private key")
+		ecdsaKey, err := generateECDSAKey()
 		if err != nil {
-			logger.Error("CreatePrivateKey", err.Error())
+			util.Logger().Error("failed to generate private key", "error", err)
 			return nil, err
 		}
+		return ecdsaKey, nil
 
 	case constants.PRIVATE_KEY_TYPE_RSA:
-		logger.Info("CreatePrivateKey", "generating RSA private key")
-		rsaKey, err := rsa.GenerateKey(rand.Reader, constants.PRIVATE_KEY_LENGTH)
+		util.Logger().Debug("generating RSA private key", "bits", constants.PRIVATE_KEY_LENGTH)
+		rsaKey, err := generateRSAKey(constants.PRIVATE_KEY_LENGTH)
 		if err != nil {
-			logger.Error("CreatePrivateKey", err.Error())
+			util.Logger().Error("failed to generate private key", "error", err)
 			return nil, err
 		}
-		privateKey = rsaKey
-
-		keyBytes = x509.MarshalPKCS1PrivateKey(rsaKey)
+		return rsaKey, nil
 
 	default:
 		return nil, fmt.Errorf("unsupported key type: %s", keyType)
 	}
+}
 
-	keyPEM := pem.EncodeToMemory(&pem.Block{
-		Type:  string(keyType),
-		Bytes: keyBytes,
-	})
-
-	// check directory exists
-	if !util.FileDirExists(keyPath) {
-		logger.Warn("CreatePrivateKey", util.FileDir(keyPath)+" directory not exists, creating...")
-		if err := util.FileDirCreate(keyPath); err != nil {
-			return nil, err
-		}
-		logger.Info("CreatePrivateKey", util.FileDir(keyPath)+" directory created")
+func generateECDSAKey() (*ecdsa.PrivateKey, error) {
+	if random := detrand.Reader(); random != nil {
+		return detrand.GenerateECDSAKey(random)
 	}
+	return ecdsa.GenerateKey(elliptic.P256(), util.RandReader())
+}
 
-	// save private key
-	if err := util.FileWrite(keyPath, keyPEM, 0644); err != nil {
-		return nil, err
+func generateRSAKey(bits int) (*rsa.PrivateKey, error) {
+	if random := detrand.Reader(); random != nil {
+		return detrand.GenerateRSAKey(random, bits)
 	}
-
-	logger.Info("CreatePrivateKey", "private key created")
-	return privateKey, nil
+	return rsa.GenerateKey(util.RandReader(), bits)
 }
@@ -1,11 +1,14 @@
 package certgo
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/rsa"
+	"path/filepath"
 	"testing"
 
 	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
 	"github.com/Alonza0314/cert-go/util"
 )
 
@@ -107,3 +110,104 @@ func TestCreatePrivateKeyRSA(t *testing.T) {
 		}
 	}
 }
+
+var testCaseCertificateKeyType = []struct {
+	name    string
+	cfg     model.Certificate
+	keyType constants.PrivateKeyType
+	expect  constants.PrivateKeyType
+	errMsg  string
+}{
+	{
+		name:   "default",
+		expect: constants.PRIVATE_KEY_TYPE_ECDSA,
+	},
+	{
+		name:   "from config",
+		cfg:    model.Certificate{KeyType: "rsa"},
+		expect: constants.PRIVATE_KEY_TYPE_RSA,
+	},
+	{
+		name:    "override",
+		cfg:     model.Certificate{KeyType: "rsa"},
+		keyType: constants.PRIVATE_KEY_TYPE_ECDSA,
+		expect:  constants.PRIVATE_KEY_TYPE_ECDSA,
+	},
+	{
+		name:    "unknown",
+		cfg:     model.Certificate{KeyType: "ed25519"},
+		keyType: constants.PRIVATE_KEY_TYPE_ECDSA,
+		errMsg:  `unknown private key type: "ed25519", expected ecdsa or rsa`,
+	},
+}
+
+func TestCertificateKeyType(t *testing.T) {
+	for _, testCase := range testCaseCertificateKeyType {
+		t.Run(testCase.name, func(t *testing.T) {
+			keyType, err := certificateKeyType(testCase.cfg, testCase.keyType)
+			if testCase.errMsg != "" {
+				if err == nil || err.Error() != testCase.errMsg {
+					t.Fatalf("TestCertificateKeyType (%s): expected error %q but got %v", testCase.name, testCase.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TestCertificateKeyType (%s): %v", testCase.name, err)
+			}
+			if keyType != testCase.expect {
+				t.Fatalf("TestCertificateKeyType (%s): actual %s != expect %s", testCase.name, keyType, testCase.expect)
+			}
+		})
+	}
+}
+
+func TestCreatePrivateKeyCA(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "root.key.pem")
+	key, err := CreatePrivateKey(keyPath, constants.PRIVATE_KEY_TYPE_ECDSA, false)
+	if err != nil {
+		t.Fatalf("TestCreatePrivateKeyCA: %v", err)
+	}
+	root, err := IssueCertificate(model.Certificate{Type: "root", IsCA: true, CommonName: "root", ValidityYears: 1}, key.(crypto.Signer).Public(), nil, key)
+	if err != nil {
+		t.Fatalf("TestCreatePrivateKeyCA: %v", err)
+	}
+	if err := util.FileWrite(filepath.Join(dir, "root.cert.pem"), util.EncodeCertificatePEM(root), 0644); err != nil {
+		t.Fatalf("TestCreatePrivateKeyCA: %v", err)
+	}
+
+	// the key of a CA certificate is only replaced with confirmation
+	if _, err := CreatePrivateKey(keyPath, constants.PRIVATE_KEY_TYPE_ECDSA, true); err == nil {
+		t.Fatalf("TestCreatePrivateKeyCA: expected error without confirmation")
+	}
+	current, err := util.ReadPrivateKey(keyPath)
+	if err != nil {
+		t.Fatalf("TestCreatePrivateKeyCA: %v", err)
+	}
+	if !publicKeyMatches(current, root.PublicKey) {
+		t.Fatalf("TestCreatePrivateKeyCA: CA private key was replaced without confirmation")
+	}
+
+	// keys of other certificates are replaced with force alone
+	leafKeyPath := filepath.Join(dir, "leaf.key.pem")
+	leafKey, err := CreatePrivateKey(leafKeyPath, constants.PRIVATE_KEY_TYPE_ECDSA, false)
+	if err != nil {
+		t.Fatalf("TestCreatePrivateKeyCA: %v", err)
+	}
+	leaf, err := IssueCertificate(model.Certificate{Type: "server", CommonName: "leaf", ValidityDay: 1}, leafKey.(crypto.Signer).Public(), root, key)
+	if err != nil {
+		t.Fatalf("TestCreatePrivateKeyCA: %v", err)
+	}
+	if err := util.FileWrite(filepath.Join(dir, "leaf.cert.pem"), util.EncodeCertificatePEM(leaf), 0644); err != nil {
+		t.Fatalf("TestCreatePrivateKeyCA: %v", err)
+	}
+	if _, err := CreatePrivateKey(leafKeyPath, constants.PRIVATE_KEY_TYPE_ECDSA, true); err != nil {
+		t.Fatalf("TestCreatePrivateKeyCA: %v", err)
+	}
+
+	SetCAOverwriteConfirm(func(path string) bool { return path == keyPath })
+	defer SetCAOverwriteConfirm(nil)
+	if _, err := CreatePrivateKey(keyPath, constants.PRIVATE_KEY_TYPE_ECDSA, true); err != nil {
+		t.Fatalf("TestCreatePrivateKeyCA: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package certgo
+
+import (
+	"crypto/x509"
+	"fmt"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+// SignProfileCertificate signs the certificate of the named profile in the yaml file.
+// The profile is looked up in ca.profiles first, then in the root, intermediate, server, client and peer slots.
+// A new private key is of keyType, or of the key_type of the profile when keyType is empty.
+func SignProfileCertificate(profile string, keyType constants.PrivateKeyType, yamlPath string, overwrite bool) (*x509.Certificate, error) {
+	cfg, err := ReadConfig(yamlPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := ResolveProfile(cfg.CA, profile)
+	if err != nil {
+		util.Logger().Error(err.Error(), "profile", profile)
+		return nil, err
+	}
+	return signCertificate(cert, keyType, overwrite)
+}
+
+// ResolveProfile returns the certificate of the named profile ready to be signed:
+// parent paths are taken from the issuer profile and key usages are parsed from
+// their names. Key usages left empty get the defaults of the profile type when signed.
+// The root, intermediate, server, client and peer slots default to the type of their name,
+// a profile without type, issuer nor parent certificate is self-signed.
+func ResolveProfile(ca model.CertificateAuthority, name string) (model.Certificate, error) {
+	cfg, ok := ca.Profile(name)
+	if !ok {
+		return model.Certificate{}, fmt.Errorf("profile not found: %s", name)
+	}
+	return resolveCertificate(ca, name, cfg)
+}
+
+// resolveCertificate resolves cfg, the certificate of the profile named name, see ResolveProfile.
+func resolveCertificate(ca model.CertificateAuthority, name string, cfg model.Certificate) (model.Certificate, error) {
+	if cfg.Issuer != "" {
+		if cfg.Issuer == name {
+			return model.Certificate{}, fmt.Errorf("profile %s cannot be its own issuer", name)
+		}
+		issuer, ok := ca.Profile(cfg.Issuer)
+		if !ok {
+			return model.Certificate{}, fmt.Errorf("issuer profile not found: %s", cfg.Issuer)
+		}
+		if !issuer.IsCA {
+			return model.Certificate{}, fmt.Errorf("issuer profile %s is not a CA", cfg.Issuer)
+		}
+		if cfg.ParentCertPath == "" {
+			cfg.ParentCertPath = issuer.CertFilePath
+		}
+		if cfg.ParentKeyPath == "" {
+			cfg.ParentKeyPath = issuer.KeyFilePath
+		}
+	}
+	if cfg.Type == "" {
+		if _, ok := ca.Profiles[name]; !ok {
+			cfg.Type = name
+		} else if cfg.ParentCertPath == "" {
+			cfg.Type = string(constants.CERT_TYPE_ROOT)
+		}
+	}
+
+	if err := applyUsage(&cfg); err != nil {
+		return model.Certificate{}, fmt.Errorf("profile %s: %w", name, err)
+	}
+
+	return cfg, nil
+}
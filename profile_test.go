@@ -0,0 +1,262 @@
+package certgo
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"reflect"
+	"testing"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+func TestSignProfileCertificate(t *testing.T) {
+	yamlPath := "./defaultCfg.yml"
+	var cfg model.CAConfig
+	if err := util.ReadYamlFileToStruct(yamlPath, &cfg); err != nil {
+		t.Fatalf("TestSignProfileCertificate: %v", err)
+	}
+
+	if _, err := SignCertificate(constants.CERT_TYPE_ROOT, constants.PRIVATE_KEY_TYPE_ECDSA, yamlPath, false); err != nil {
+		t.Fatalf("TestSignProfileCertificate: %v", err)
+	}
+	intermediate, err := SignCertificate(constants.CERT_TYPE_INTERMEDIATE, constants.PRIVATE_KEY_TYPE_ECDSA, yamlPath, false)
+	if err != nil {
+		t.Fatalf("TestSignProfileCertificate: %v", err)
+	}
+
+	cert, err := SignProfileCertificate("api-gateway", constants.PRIVATE_KEY_TYPE_ECDSA, yamlPath, false)
+	if err != nil {
+		t.Fatalf("TestSignProfileCertificate: %v", err)
+	}
+	if err := cert.CheckSignatureFrom(intermediate); err != nil {
+		t.Fatalf("TestSignProfileCertificate: profile should be issued by the intermediate certificate: %v", err)
+	}
+	if cert.KeyUsage != x509.KeyUsageDigitalSignature {
+		t.Fatalf("TestSignProfileCertificate: unexpected key usage %v", cert.KeyUsage)
+	}
+	if !reflect.DeepEqual(cert.ExtKeyUsage, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}) {
+		t.Fatalf("TestSignProfileCertificate: unexpected extended key usage %v", cert.ExtKeyUsage)
+	}
+
+	if _, err := SignProfileCertificate("api-gateway", constants.PRIVATE_KEY_TYPE_ECDSA, yamlPath, false); err == nil || err.Error() != "certificate already exists" {
+		t.Fatalf("TestSignProfileCertificate: expected error for existing certificate without force")
+	}
+
+	profile := cfg.CA.Profiles["api-gateway"]
+	for _, c := range []model.Certificate{cfg.CA.Root, cfg.CA.Intermediate, profile} {
+		for _, path := range []string{c.CertFilePath, c.CsrFilePath, c.KeyFilePath} {
+			if path == "" {
+				continue
+			}
+			if err := util.FileDelete(path); err != nil {
+				t.Fatalf("TestSignProfileCertificate: %v", err)
+			}
+		}
+	}
+}
+
+var testCaseResolveProfile = []struct {
+	name    string
+	ca      model.CertificateAuthority
+	profile string
+	errMsg  string
+}{
+	{
+		name:    "profile not found",
+		ca:      model.CertificateAuthority{},
+		profile: "kafka-broker",
+		errMsg:  "profile not found: kafka-broker",
+	},
+	{
+		name: "issuer not found",
+		ca: model.CertificateAuthority{
+			Profiles: map[string]model.Certificate{"kafka-broker": {Issuer: "kafka-ca"}},
+		},
+		profile: "kafka-broker",
+		errMsg:  "issuer profile not found: kafka-ca",
+	},
+	{
+		name: "issuer is not a CA",
+		ca: model.CertificateAuthority{
+			Profiles: map[string]model.Certificate{"kafka-broker": {Issuer: "server"}},
+		},
+		profile: "kafka-broker",
+		errMsg:  "issuer profile server is not a CA",
+	},
+	{
+		name: "unknown key usage",
+		ca: model.CertificateAuthority{
+			Profiles: map[string]model.Certificate{"kafka-broker": {KeyUsageNames: []string{"signEverything"}}},
+		},
+		profile: "kafka-broker",
+		errMsg:  "profile kafka-broker: unknown key usage: signEverything",
+	},
+}
+
+func TestResolveProfile(t *testing.T) {
+	for _, testCase := range testCaseResolveProfile {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := ResolveProfile(testCase.ca, testCase.profile)
+			if err == nil || err.Error() != testCase.errMsg {
+				t.Fatalf("TestResolveProfile (%s): expected error %q but got %v", testCase.name, testCase.errMsg, err)
+			}
+		})
+	}
+}
+
+var testCaseResolveProfileUsage = []struct {
+	name              string
+	cfg               model.Certificate
+	expectKeyUsage    x509.KeyUsage
+	expectExtKeyUsage []x509.ExtKeyUsage
+	expectOIDs        []asn1.ObjectIdentifier
+}{
+	{
+		name: "server with explicit usages",
+		cfg: model.Certificate{
+			Type:             "server",
+			ParentCertPath:   "parent.pem",
+			KeyUsageNames:    []string{"digitalSignature"},
+			ExtKeyUsageNames: []string{"serverAuth", "1.3.6.1.5.5.7.3.17"},
+		},
+		expectKeyUsage:    x509.KeyUsageDigitalSignature,
+		expectExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		expectOIDs:        []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 17}},
+	},
+}
+
+func TestResolveProfileUsage(t *testing.T) {
+	for _, testCase := range testCaseResolveProfileUsage {
+		t.Run(testCase.name, func(t *testing.T) {
+			ca := model.CertificateAuthority{Profiles: map[string]model.Certificate{"test": testCase.cfg}}
+			cfg, err := ResolveProfile(ca, "test")
+			if err != nil {
+				t.Fatalf("TestResolveProfileUsage (%s): %v", testCase.name, err)
+			}
+			if cfg.KeyUsage != testCase.expectKeyUsage {
+				t.Fatalf("TestResolveProfileUsage (%s): key usage %v != %v", testCase.name, cfg.KeyUsage, testCase.expectKeyUsage)
+			}
+			if !reflect.DeepEqual(cfg.ExtKeyUsage, testCase.expectExtKeyUsage) {
+				t.Fatalf("TestResolveProfileUsage (%s): extended key usage %v != %v", testCase.name, cfg.ExtKeyUsage, testCase.expectExtKeyUsage)
+			}
+			if !reflect.DeepEqual(cfg.UnknownExtKeyUsage, testCase.expectOIDs) {
+				t.Fatalf("TestResolveProfileUsage (%s): raw extended key usage %v != %v", testCase.name, cfg.UnknownExtKeyUsage, testCase.expectOIDs)
+			}
+		})
+	}
+}
+
+var testCaseResolveProfileDefaultUsage = []struct {
+	name              string
+	cfg               model.Certificate
+	keyType           constants.PrivateKeyType
+	expectKeyUsage    x509.KeyUsage
+	expectExtKeyUsage []x509.ExtKeyUsage
+}{
+	{
+		name:              "server defaults",
+		cfg:               model.Certificate{Type: "server", ParentCertPath: "parent.pem"},
+		keyType:           constants.PRIVATE_KEY_TYPE_ECDSA,
+		expectKeyUsage:    x509.KeyUsageDigitalSignature,
+		expectExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	},
+	{
+		name:              "server defaults with rsa key",
+		cfg:               model.Certificate{Type: "server", ParentCertPath: "parent.pem"},
+		keyType:           constants.PRIVATE_KEY_TYPE_RSA,
+		expectKeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		expectExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	},
+	{
+		name:              "self-signed defaults",
+		cfg:               model.Certificate{IsCA: true},
+		keyType:           constants.PRIVATE_KEY_TYPE_ECDSA,
+		expectKeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		expectExtKeyUsage: nil,
+	},
+}
+
+func TestResolveProfileDefaultUsage(t *testing.T) {
+	for _, testCase := range testCaseResolveProfileDefaultUsage {
+		t.Run(testCase.name, func(t *testing.T) {
+			ca := model.CertificateAuthority{Profiles: map[string]model.Certificate{"test": testCase.cfg}}
+			cfg, err := ResolveProfile(ca, "test")
+			if err != nil {
+				t.Fatalf("TestResolveProfileDefaultUsage (%s): %v", testCase.name, err)
+			}
+
+			key, err := GeneratePrivateKey(testCase.keyType)
+			if err != nil {
+				t.Fatalf("TestResolveProfileDefaultUsage (%s): %v", testCase.name, err)
+			}
+			cfg.CommonName, cfg.ValidityDay = "test", 1
+			cert, err := IssueCertificate(cfg, key.(crypto.Signer).Public(), nil, key)
+			if err != nil {
+				t.Fatalf("TestResolveProfileDefaultUsage (%s): %v", testCase.name, err)
+			}
+			if cert.KeyUsage != testCase.expectKeyUsage {
+				t.Fatalf("TestResolveProfileDefaultUsage (%s): key usage %v != %v", testCase.name, cert.KeyUsage, testCase.expectKeyUsage)
+			}
+			if !reflect.DeepEqual(cert.ExtKeyUsage, testCase.expectExtKeyUsage) {
+				t.Fatalf("TestResolveProfileDefaultUsage (%s): extended key usage %v != %v", testCase.name, cert.ExtKeyUsage, testCase.expectExtKeyUsage)
+			}
+		})
+	}
+}
+
+var testCaseResolveProfileSlotType = []struct {
+	name              string
+	ca                model.CertificateAuthority
+	expectType        string
+	expectExtKeyUsage []x509.ExtKeyUsage
+}{
+	{
+		name:              "server",
+		ca:                model.CertificateAuthority{Server: model.Certificate{CommonName: "server", ParentCertPath: "parent.pem"}},
+		expectType:        "server",
+		expectExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	},
+	{
+		name:              "client",
+		ca:                model.CertificateAuthority{Client: model.Certificate{CommonName: "client", ParentCertPath: "parent.pem"}},
+		expectType:        "client",
+		expectExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	},
+	{
+		name:              "peer",
+		ca:                model.CertificateAuthority{Peer: model.Certificate{CommonName: "peer", ParentCertPath: "parent.pem"}},
+		expectType:        "peer",
+		expectExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	},
+}
+
+func TestResolveProfileSlotType(t *testing.T) {
+	for _, testCase := range testCaseResolveProfileSlotType {
+		t.Run(testCase.name, func(t *testing.T) {
+			cfg, err := ResolveProfile(testCase.ca, testCase.name)
+			if err != nil {
+				t.Fatalf("TestResolveProfileSlotType (%s): %v", testCase.name, err)
+			}
+			if cfg.Type != testCase.expectType {
+				t.Fatalf("TestResolveProfileSlotType (%s): type %q != %q", testCase.name, cfg.Type, testCase.expectType)
+			}
+
+			key, err := GeneratePrivateKey(constants.PRIVATE_KEY_TYPE_ECDSA)
+			if err != nil {
+				t.Fatalf("TestResolveProfileSlotType (%s): %v", testCase.name, err)
+			}
+			cfg.ValidityDay = 1
+			cert, err := IssueCertificate(cfg, &key.(*ecdsa.PrivateKey).PublicKey, nil, key)
+			if err != nil {
+				t.Fatalf("TestResolveProfileSlotType (%s): %v", testCase.name, err)
+			}
+			if !reflect.DeepEqual(cert.ExtKeyUsage, testCase.expectExtKeyUsage) {
+				t.Fatalf("TestResolveProfileSlotType (%s): extended key usage %v != %v", testCase.name, cert.ExtKeyUsage, testCase.expectExtKeyUsage)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package certgo
+
+import (
+	"io"
+	"time"
+
+	"github.com/Alonza0314/cert-go/util"
+)
+
+// Clock reports the current time, it is used to compute validity windows.
+type Clock = util.Clock
+
+// FixedClock is a Clock that always reports the same time.
+type FixedClock = util.FixedClock
+
+// DeterministicEpoch is the time reported by the clock installed by certgotest.SetDeterministic.
+var DeterministicEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// SetRandReader sets the source of randomness passed to crypto/ecdsa, crypto/rsa and crypto/x509
+// for keys, serial numbers and signatures. A nil reader restores crypto/rand.Reader.
+// The output is not reproducible and any reader other than crypto/rand.Reader must not be
+// used for production keys, tests needing byte-identical output use certgotest.SetDeterministic.
+func SetRandReader(r io.Reader) {
+	util.SetRandReader(r)
+}
+
+// SetClock sets the clock used for validity windows. A nil clock restores the system clock.
+func SetClock(c Clock) {
+	util.SetClock(c)
+}
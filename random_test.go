@@ -0,0 +1,87 @@
+package certgo
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/internal/detrand"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+var testCaseDeterministic = []struct {
+	name    string
+	keyType constants.PrivateKeyType
+}{
+	{
+		name:    "ecdsa",
+		keyType: constants.PRIVATE_KEY_TYPE_ECDSA,
+	},
+	{
+		name:    "rsa",
+		keyType: constants.PRIVATE_KEY_TYPE_RSA,
+	},
+}
+
+func signDeterministicChain(t *testing.T, seed int64, keyType constants.PrivateKeyType) []byte {
+	yamlPath := "./defaultCfg.yml"
+	var cfg model.CAConfig
+	if err := util.ReadYamlFileToStruct(yamlPath, &cfg); err != nil {
+		t.Fatalf("signDeterministicChain: %v", err)
+	}
+
+	restore := detrand.Enable(seed)
+	defer restore()
+	SetClock(FixedClock(DeterministicEpoch))
+	defer SetClock(nil)
+
+	if _, err := SignCertificate(constants.CERT_TYPE_ROOT, keyType, yamlPath, false); err != nil {
+		t.Fatalf("signDeterministicChain: %v", err)
+	}
+	if _, err := SignCertificate(constants.CERT_TYPE_INTERMEDIATE, keyType, yamlPath, false); err != nil {
+		t.Fatalf("signDeterministicChain: %v", err)
+	}
+	cert, err := SignCertificate(constants.CERT_TYPE_SERVER, keyType, yamlPath, false)
+	if err != nil {
+		t.Fatalf("signDeterministicChain: %v", err)
+	}
+	if !cert.NotBefore.Equal(DeterministicEpoch) {
+		t.Fatalf("signDeterministicChain: not before %s != %s", cert.NotBefore, DeterministicEpoch)
+	}
+
+	var out []byte
+	for _, c := range []model.Certificate{cfg.CA.Root, cfg.CA.Intermediate, cfg.CA.Server} {
+		for _, path := range []string{c.KeyFilePath, c.CsrFilePath, c.CertFilePath} {
+			if path == "" {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("signDeterministicChain: %v", err)
+			}
+			out = append(out, data...)
+			if err := util.FileDelete(path); err != nil {
+				t.Fatalf("signDeterministicChain: %v", err)
+			}
+		}
+	}
+	return out
+}
+
+func TestDeterministic(t *testing.T) {
+	for _, testCase := range testCaseDeterministic {
+		t.Run(testCase.name, func(t *testing.T) {
+			first := signDeterministicChain(t, 1, testCase.keyType)
+			second := signDeterministicChain(t, 1, testCase.keyType)
+			other := signDeterministicChain(t, 2, testCase.keyType)
+			if !bytes.Equal(first, second) {
+				t.Fatalf("TestDeterministic (%s): same seed should give the same artifacts", testCase.name)
+			}
+			if bytes.Equal(first, other) {
+				t.Fatalf("TestDeterministic (%s): different seeds should give different artifacts", testCase.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package certgo
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+// RenewOptions configures RenewCertificate.
+type RenewOptions struct {
+	// KeyPath is the private key of the certificate.
+	KeyPath string
+	// IssuerCertPath and IssuerKeyPath are the certificate and private key of the issuer,
+	// both are left empty for a self-signed certificate.
+	IssuerCertPath string
+	IssuerKeyPath  string
+	// RotateKey replaces the private key at KeyPath by a new one of KeyType,
+	// or of the type of the current key when KeyType is empty.
+	RotateKey bool
+	KeyType   constants.PrivateKeyType
+	// Window renews the certificate only when it expires within Window, zero renews it in any case.
+	Window time.Duration
+	// Validity is the lifetime of the new certificate, zero keeps the lifetime of the current one.
+	Validity time.Duration
+	// Backdate moves the not before of the new certificate back to tolerate clock skew.
+	Backdate time.Duration
+	// StrictValidity fails instead of clamping a not after beyond the one of the issuer.
+	StrictValidity bool
+	// Name and Hooks are the name of the certificate in its configuration and the hooks run on its
+	// key_created and cert_renewed events.
+	Name  string
+	Hooks []model.Hook
+	// Policy is the policy of the issuer the new certificate is checked against, nil checks nothing.
+	Policy *model.Policy
+}
+
+// extensions x509.CreateCertificate builds from the template fields, the others are copied as they are.
+var templateExtensions = []asn1.ObjectIdentifier{
+	{2, 5, 29, 14},              // subject key id
+	{2, 5, 29, 35},              // authority key id
+	{2, 5, 29, 15},              // key usage
+	{2, 5, 29, 37},              // extended key usage
+	{2, 5, 29, 19},              // basic constraints
+	{2, 5, 29, 17},              // subject alternative name
+	{2, 5, 29, 30},              // name constraints
+	{2, 5, 29, 31},              // crl distribution points
+	{2, 5, 29, 32},              // certificate policies
+	{1, 3, 6, 1, 5, 5, 7, 1, 1}, // authority information access
+}
+
+// RenewCertificate reissues the certificate at certPath with the subject, subject alternative names
+// and extensions of the current one, signed by the issuer that signed it, and replaces it. Nothing is
+// read from the configuration so the identity of the certificate cannot drift. The current certificate
+// is returned with false when it does not expire within the renewal window.
+func RenewCertificate(certPath string, opts RenewOptions) (*x509.Certificate, bool, error) {
+	current, err := util.ReadCertificate(certPath)
+	if err != nil {
+		return nil, false, err
+	}
+	if opts.Window > 0 && util.Now().Add(opts.Window).Before(current.NotAfter) {
+		util.Logger().Info("certificate does not expire within the renewal window, skip it", "path", certPath, "not_after", current.NotAfter, "window", opts.Window)
+		return current, false, nil
+	}
+
+	if opts.KeyPath == "" {
+		return nil, false, errors.New("private key path is required")
+	}
+	if opts.RotateKey && current.IsCA {
+		if err := confirmCAOverwrite(opts.KeyPath); err != nil {
+			return nil, false, err
+		}
+	}
+	var key interface{}
+	if !opts.RotateKey || util.FileExists(opts.KeyPath) {
+		if key, err = util.ReadPrivateKey(opts.KeyPath); err != nil {
+			return nil, false, err
+		}
+	}
+	if opts.RotateKey {
+		keyType := opts.KeyType
+		if keyType == "" {
+			keyType = constants.PRIVATE_KEY_TYPE_ECDSA
+			if key != nil {
+				keyType = util.GetPrivateKeyType(key)
+			}
+		}
+		if key, err = GeneratePrivateKey(keyType); err != nil {
+			return nil, false, err
+		}
+	} else if !publicKeyMatches(key, current.PublicKey) {
+		util.Logger().Error("private key does not match the certificate", "path", opts.KeyPath, "cert", certPath)
+		return nil, false, errors.New("private key does not match the certificate")
+	}
+
+	var issuerCert *x509.Certificate
+	issuerKey := key
+	if opts.IssuerCertPath != "" {
+		if opts.IssuerKeyPath == "" {
+			return nil, false, errors.New("issuer private key path is required")
+		}
+		if issuerCert, err = util.ReadCertificate(opts.IssuerCertPath); err != nil {
+			return nil, false, err
+		}
+		if issuerKey, err = util.ReadPrivateKey(opts.IssuerKeyPath); err != nil {
+			return nil, false, err
+		}
+		if err := current.CheckSignatureFrom(issuerCert); err != nil {
+			util.Logger().Error("certificate is not signed by the issuer", "path", certPath, "issuer", opts.IssuerCertPath, "error", err)
+			return nil, false, fmt.Errorf("certificate is not signed by the issuer: %w", err)
+		}
+		if !publicKeyMatches(issuerKey, issuerCert.PublicKey) {
+			util.Logger().Error("issuer private key does not match the issuer certificate", "path", opts.IssuerKeyPath, "issuer", opts.IssuerCertPath)
+			return nil, false, errors.New("issuer private key does not match the issuer certificate")
+		}
+	} else if err := current.CheckSignature(current.SignatureAlgorithm, current.RawTBSCertificate, current.Signature); err != nil {
+		util.Logger().Error("certificate is not self-signed, the issuer is required", "path", certPath)
+		return nil, false, errors.New("certificate is not self-signed, the issuer certificate and private key are required")
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, false, errors.New("unsupported private key")
+	}
+	template, err := renewalTemplate(current, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := checkPolicy(opts.Policy, template, signer.Public(), certPath); err != nil {
+		return nil, false, err
+	}
+	cert, err := signTemplate(template, signer.Public(), issuerCert, issuerKey, opts.StrictValidity, certPath)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := replaceRenewedFiles(certPath, cert, opts.KeyPath, key, opts.RotateKey); err != nil {
+		return nil, false, err
+	}
+
+	util.Logger().Info("certificate renewed",
+		"serial", cert.SerialNumber.Text(16),
+		"subject", cert.Subject.String(),
+		"issuer", cert.Issuer.String(),
+		"not_before", cert.NotBefore,
+		"not_after", cert.NotAfter,
+		"path", certPath,
+	)
+
+	// the events are emitted once both files are in place, an aborting hook cannot leave them mismatched
+	hookCfg := model.Certificate{Name: opts.Name, CertFilePath: certPath, KeyFilePath: opts.KeyPath, Hooks: opts.Hooks}
+	if opts.RotateKey {
+		if err := recordKeyCreated(hookCfg, key); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := recordEvent(opts.Hooks, newHookPayload(constants.HOOK_EVENT_CERT_RENEWED, hookCfg, cert, nil)); err != nil {
+		return nil, false, err
+	}
+	return cert, true, nil
+}
+
+// replaceRenewedFiles writes the renewed certificate and, when rotateKey is set, its new key. The key is
+// staged first and renamed once the certificate is written, the previous certificate is put back if the
+// rename fails, so the key and the certificate on disk always match.
+func replaceRenewedFiles(certPath string, cert *x509.Certificate, keyPath string, key interface{}, rotateKey bool) error {
+	if !rotateKey {
+		return util.FileWriteAtomic(certPath, util.EncodeCertificatePEM(cert), 0644)
+	}
+
+	keyPEM, err := util.EncodePrivateKeyPEM(key)
+	if err != nil {
+		return err
+	}
+	previous, err := os.ReadFile(certPath)
+	if err != nil {
+		util.Logger().Error("failed to read file", "path", certPath, "error", err)
+		return err
+	}
+	stagedKey, err := util.StagePrivateKeyFile(keyPath, keyPEM)
+	if err != nil {
+		return err
+	}
+	if err := util.FileWriteAtomic(certPath, util.EncodeCertificatePEM(cert), 0644); err != nil {
+		_ = os.Remove(stagedKey)
+		return err
+	}
+	if err := util.FileCommit(stagedKey, keyPath); err != nil {
+		if rollbackErr := util.FileWriteAtomic(certPath, previous, 0644); rollbackErr != nil {
+			util.Logger().Error("failed to restore the previous certificate, it does not match the private key", "path", certPath, "error", rollbackErr)
+		}
+		return err
+	}
+	util.Logger().Info("private key rotated", "path", keyPath, "key_type", string(util.GetPrivateKeyType(key)))
+	return nil
+}
+
+// renewalTemplate returns the template of the renewal of current, with a new serial number and validity.
+func renewalTemplate(current *x509.Certificate, opts RenewOptions) (*x509.Certificate, error) {
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	lifetime := opts.Validity
+	if lifetime == 0 {
+		lifetime = current.NotAfter.Sub(current.NotBefore)
+	}
+	now := util.Now()
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               current.Subject,
+		RawSubject:            current.RawSubject,
+		NotBefore:             now.Add(-opts.Backdate),
+		NotAfter:              now.Add(lifetime),
+		KeyUsage:              current.KeyUsage,
+		ExtKeyUsage:           current.ExtKeyUsage,
+		UnknownExtKeyUsage:    current.UnknownExtKeyUsage,
+		BasicConstraintsValid: current.BasicConstraintsValid,
+		IsCA:                  current.IsCA,
+		MaxPathLen:            current.MaxPathLen,
+		MaxPathLenZero:        current.MaxPathLenZero,
+		DNSNames:              current.DNSNames,
+		EmailAddresses:        current.EmailAddresses,
+		IPAddresses:           current.IPAddresses,
+		URIs:                  current.URIs,
+
+		PermittedDNSDomainsCritical: current.PermittedDNSDomainsCritical,
+		PermittedDNSDomains:         current.PermittedDNSDomains,
+		ExcludedDNSDomains:          current.ExcludedDNSDomains,
+		PermittedIPRanges:           current.PermittedIPRanges,
+		ExcludedIPRanges:            current.ExcludedIPRanges,
+		PermittedEmailAddresses:     current.PermittedEmailAddresses,
+		ExcludedEmailAddresses:      current.ExcludedEmailAddresses,
+		PermittedURIDomains:         current.PermittedURIDomains,
+		ExcludedURIDomains:          current.ExcludedURIDomains,
+
+		CRLDistributionPoints: current.CRLDistributionPoints,
+		OCSPServer:            current.OCSPServer,
+		IssuingCertificateURL: current.IssuingCertificateURL,
+		PolicyIdentifiers:     current.PolicyIdentifiers,
+	}
+	if !opts.RotateKey {
+		template.SubjectKeyId = current.SubjectKeyId
+	}
+
+	for _, ext := range current.Extensions {
+		copied := true
+		for _, oid := range templateExtensions {
+			if ext.Id.Equal(oid) {
+				copied = false
+				break
+			}
+		}
+		if copied {
+			template.ExtraExtensions = append(template.ExtraExtensions, ext)
+		}
+	}
+	return template, nil
+}
+
+// RenewOptionsFromConfig returns the options to renew the certificate at certPath, taken from the entry
+// of the configuration file at yamlPath with the same cert path: its private key, issuer, key_type,
+// backdate, strict_validity and the policy of its issuer. The subject and extensions still come from the certificate itself.
+func RenewOptionsFromConfig(yamlPath, certPath string) (RenewOptions, error) {
+	cfg, err := ReadConfig(yamlPath)
+	if err != nil {
+		return RenewOptions{}, err
+	}
+	certs, err := initOrder(cfg.CA)
+	if err != nil {
+		return RenewOptions{}, err
+	}
+
+	target, err := filepath.Abs(certPath)
+	if err != nil {
+		return RenewOptions{}, err
+	}
+	for _, c := range certs {
+		path, err := filepath.Abs(c.cfg.CertFilePath)
+		if err != nil || path != target {
+			continue
+		}
+		return renewOptions(c)
+	}
+	return RenewOptions{}, fmt.Errorf("no certificate of the configuration is at %s", certPath)
+}
+
+// renewOptions returns the options to renew the certificate c of a configuration.
+func renewOptions(c initCert) (RenewOptions, error) {
+	opts := RenewOptions{
+		KeyPath:        c.cfg.KeyFilePath,
+		StrictValidity: c.cfg.StrictValidity,
+		Name:           c.name,
+		Hooks:          c.cfg.Hooks,
+		Policy:         c.cfg.IssuerPolicy,
+	}
+	if c.cfg.Type != string(constants.CERT_TYPE_ROOT) {
+		opts.IssuerCertPath, opts.IssuerKeyPath = c.cfg.ParentCertPath, c.cfg.ParentKeyPath
+	}
+	var err error
+	if c.cfg.KeyType != "" {
+		if opts.KeyType, err = util.ParsePrivateKeyType(c.cfg.KeyType); err != nil {
+			return RenewOptions{}, fmt.Errorf("%s: %w", c.name, err)
+		}
+	}
+	if c.cfg.Backdate != "" {
+		if opts.Backdate, err = util.ParseDuration(c.cfg.Backdate); err != nil {
+			return RenewOptions{}, fmt.Errorf("%s: backdate: %w", c.name, err)
+		}
+	}
+	return opts, nil
+}
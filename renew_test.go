@@ -0,0 +1,125 @@
+package certgo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+func TestRenewCertificate(t *testing.T) {
+	yamlPath := "./defaultCfg.yml"
+	certPath := "./default_ca/server/server.cert.pem"
+	defer func() {
+		if err := os.RemoveAll("./default_ca"); err != nil {
+			t.Fatalf("TestRenewCertificate: %v", err)
+		}
+	}()
+
+	if _, err := InitPKI(yamlPath, ""); err != nil {
+		t.Fatalf("TestRenewCertificate: %v", err)
+	}
+	current, err := util.ReadCertificate(certPath)
+	if err != nil {
+		t.Fatalf("TestRenewCertificate: %v", err)
+	}
+	opts, err := RenewOptionsFromConfig(yamlPath, certPath)
+	if err != nil {
+		t.Fatalf("TestRenewCertificate: %v", err)
+	}
+	if opts.KeyPath != "default_ca/server/server.key.pem" || opts.IssuerCertPath != "default_ca/intermediate/intermediate.cert.pem" {
+		t.Fatalf("TestRenewCertificate: unexpected options from the configuration: %+v", opts)
+	}
+
+	// far from expiry, the certificate is kept
+	opts.Window = 24 * time.Hour
+	cert, renewed, err := RenewCertificate(certPath, opts)
+	if err != nil {
+		t.Fatalf("TestRenewCertificate: %v", err)
+	}
+	if renewed || cert.SerialNumber.Cmp(current.SerialNumber) != 0 {
+		t.Fatalf("TestRenewCertificate: certificate outside the renewal window was renewed")
+	}
+
+	opts.Window = 0
+	cert, renewed, err = RenewCertificate(certPath, opts)
+	if err != nil {
+		t.Fatalf("TestRenewCertificate: %v", err)
+	}
+	if !renewed || cert.SerialNumber.Cmp(current.SerialNumber) == 0 {
+		t.Fatalf("TestRenewCertificate: certificate was not renewed")
+	}
+	if !bytes.Equal(cert.RawSubject, current.RawSubject) || !reflect.DeepEqual(cert.DNSNames, current.DNSNames) || !reflect.DeepEqual(cert.ExtKeyUsage, current.ExtKeyUsage) {
+		t.Fatalf("TestRenewCertificate: identity of the certificate changed: %v %v", cert.Subject, cert.DNSNames)
+	}
+	if !reflect.DeepEqual(cert.PublicKey, current.PublicKey) {
+		t.Fatalf("TestRenewCertificate: private key was not reused")
+	}
+	issuer, err := util.ReadCertificate(opts.IssuerCertPath)
+	if err != nil {
+		t.Fatalf("TestRenewCertificate: %v", err)
+	}
+	if err := cert.CheckSignatureFrom(issuer); err != nil {
+		t.Fatalf("TestRenewCertificate: renewed certificate is not signed by the issuer: %v", err)
+	}
+
+	opts.RotateKey, opts.KeyType = true, constants.PRIVATE_KEY_TYPE_RSA
+	cert, _, err = RenewCertificate(certPath, opts)
+	if err != nil {
+		t.Fatalf("TestRenewCertificate: %v", err)
+	}
+	key, err := util.ReadPrivateKey(opts.KeyPath)
+	if err != nil {
+		t.Fatalf("TestRenewCertificate: %v", err)
+	}
+	if util.GetPrivateKeyType(key) != constants.PRIVATE_KEY_TYPE_RSA || !publicKeyMatches(key, cert.PublicKey) {
+		t.Fatalf("TestRenewCertificate: private key was not rotated")
+	}
+
+	// an aborting hook fails the renewal once the key and the certificate are both replaced
+	opts.Hooks = []model.Hook{{Events: []string{"key_created"}, Exec: []string{"false"}, OnFailure: "abort"}}
+	if _, _, err := RenewCertificate(certPath, opts); err == nil {
+		t.Fatalf("TestRenewCertificate: expected error of the aborting hook")
+	}
+	opts.Hooks = nil
+	if cert, err = util.ReadCertificate(certPath); err != nil {
+		t.Fatalf("TestRenewCertificate: %v", err)
+	}
+	if key, err = util.ReadPrivateKey(opts.KeyPath); err != nil {
+		t.Fatalf("TestRenewCertificate: %v", err)
+	}
+	if !publicKeyMatches(key, cert.PublicKey) {
+		t.Fatalf("TestRenewCertificate: private key does not match the certificate after an aborting hook")
+	}
+	entries, err := os.ReadDir(filepath.Dir(certPath))
+	if err != nil {
+		t.Fatalf("TestRenewCertificate: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Fatalf("TestRenewCertificate: staged file %s left behind", entry.Name())
+		}
+	}
+
+	// the root did not sign the server certificate
+	opts.RotateKey = false
+	opts.IssuerCertPath, opts.IssuerKeyPath = "./default_ca/root/root.cert.pem", "./default_ca/root/root.key.pem"
+	if _, _, err := RenewCertificate(certPath, opts); err == nil {
+		t.Fatalf("TestRenewCertificate: renewed with the wrong issuer")
+	}
+	opts.IssuerCertPath, opts.IssuerKeyPath = "", ""
+	if _, _, err := RenewCertificate(certPath, opts); err == nil {
+		t.Fatalf("TestRenewCertificate: renewed without the issuer")
+	}
+
+	if _, err := RenewOptionsFromConfig(yamlPath, "./default_ca/unknown.cert.pem"); err == nil {
+		t.Fatalf("TestRenewCertificate: found options of an unknown certificate")
+	}
+}
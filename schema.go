@@ -0,0 +1,74 @@
+package certgo
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/Alonza0314/cert-go/model"
+)
+
+// ConfigSchemaID is the id of the JSON Schema published at the root of the repository.
+const ConfigSchemaID = "https://raw.githubusercontent.com/Alonza0314/cert-go/main/config.schema.json"
+
+// ConfigSchema returns the JSON Schema of the configuration files, generated from the yaml tags
+// of model.CAConfig. It applies to the yaml, json and toml formats alike.
+func ConfigSchema() ([]byte, error) {
+	defs := make(map[string]interface{})
+	schema := jsonSchema(reflect.TypeOf(model.CAConfig{}), defs, true)
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = ConfigSchemaID
+	schema["title"] = "cert-go configuration"
+	schema["$defs"] = defs
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// jsonSchema returns the schema of t. Structs other than the top level one are added to defs and referenced.
+func jsonSchema(t reflect.Type, defs map[string]interface{}, inline bool) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": jsonSchema(t.Elem(), defs, false)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchema(t.Elem(), defs, false)}
+	case reflect.Ptr:
+		return jsonSchema(t.Elem(), defs, inline)
+	case reflect.Struct:
+		if !inline {
+			if _, ok := defs[t.Name()]; !ok {
+				// registered before its fields in case of recursion
+				defs[t.Name()] = nil
+				defs[t.Name()] = jsonSchema(t, defs, true)
+			}
+			return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+		}
+
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			properties[name] = jsonSchema(field.Type, defs, false)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,22 @@
+package certgo
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestConfigSchema(t *testing.T) {
+	schema, err := ConfigSchema()
+	if err != nil {
+		t.Fatalf("TestConfigSchema: %v", err)
+	}
+
+	published, err := os.ReadFile("./config.schema.json")
+	if err != nil {
+		t.Fatalf("TestConfigSchema: %v", err)
+	}
+	if !bytes.Equal(schema, published) {
+		t.Fatalf("TestConfigSchema: config.schema.json is out of date, run `cert-go config schema -o config.schema.json`")
+	}
+}
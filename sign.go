@@ -0,0 +1,102 @@
+package certgo
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+// SignCsr signs the csr of a third party at csrPath and writes the certificate to certPath. The profile
+// of the yaml file gives the type, validity and key usages, the subject and subject alternative names
+// come from the csr and are checked against the policy of the issuer. issuer names the issuer profile,
+// an empty issuer keeps the one of the profile.
+func SignCsr(csrPath, certPath, issuer, profile, yamlPath string, overwrite bool) (*x509.Certificate, error) {
+	cfg, err := ReadConfig(yamlPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, ok := cfg.CA.Profile(profile)
+	if !ok {
+		util.Logger().Error("profile not found", "profile", profile)
+		return nil, fmt.Errorf("profile not found: %s", profile)
+	}
+	if issuer != "" {
+		cert.Issuer, cert.ParentCertPath, cert.ParentKeyPath = issuer, "", ""
+		cert.IssuerPolicy = issuerPolicy(cfg.CA, cert)
+	}
+	if cert, err = resolveCertificate(cfg.CA, profile, cert); err != nil {
+		util.Logger().Error(err.Error(), "profile", profile)
+		return nil, err
+	}
+	if cert.ParentCertPath == "" || cert.ParentKeyPath == "" {
+		util.Logger().Error("profile has no issuer", "profile", profile)
+		return nil, fmt.Errorf("profile %s has no issuer, specify the issuer profile", profile)
+	}
+	cert.CertFilePath, cert.CsrFilePath, cert.KeyFilePath = certPath, csrPath, ""
+
+	if util.FileExists(certPath) {
+		if !overwrite {
+			util.Logger().Error("certificate already exists", "path", certPath)
+			return nil, errors.New("certificate already exists")
+		}
+		if cert.IsCA {
+			if err := confirmCAOverwrite(certPath); err != nil {
+				return nil, err
+			}
+		}
+		// replaced only once the new certificate is written
+		util.Logger().Warn("certificate already exists, overwrite it", "path", certPath)
+	}
+
+	csr, err := util.ReadCsr(csrPath)
+	if err != nil {
+		return nil, err
+	}
+	if cert.ParentCert, err = util.ReadCertificate(cert.ParentCertPath); err != nil {
+		return nil, err
+	}
+	if cert.ParentKey, err = util.ReadPrivateKey(cert.ParentKeyPath); err != nil {
+		return nil, err
+	}
+
+	signed, err := IssueCertificateFromCsr(csr, cert, cert.ParentCert, cert.ParentKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := saveCertificate(cert, signed); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+// IssueCertificateFromCsr signs a certificate for the csr of a third party in memory, nothing is written
+// to disk. The subject and subject alternative names come from csr, the validity, key usages and CA flag
+// from cfg, whose own subject and names are ignored. The certificate is checked against cfg.IssuerPolicy
+// first, see IssueCertificate.
+func IssueCertificateFromCsr(csr *x509.CertificateRequest, cfg model.Certificate, parentCert *x509.Certificate, parentKey interface{}) (*x509.Certificate, error) {
+	if parentCert == nil {
+		return nil, errors.New("issuer certificate is required")
+	}
+	if err := csr.CheckSignature(); err != nil {
+		util.Logger().Error("invalid csr signature", "path", cfg.CsrFilePath, "error", err)
+		return nil, fmt.Errorf("invalid csr signature: %w", err)
+	}
+	cfg.Organization, cfg.CommonName = "", ""
+	cfg.DNSNames, cfg.IPAddresses, cfg.URIs = nil, nil, nil
+	template, err := certificateTemplate(cfg, csr.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	template.Subject, template.RawSubject = csr.Subject, csr.RawSubject
+	template.DNSNames, template.IPAddresses = csr.DNSNames, csr.IPAddresses
+	template.URIs, template.EmailAddresses = csr.URIs, csr.EmailAddresses
+
+	if err := checkPolicy(cfg.IssuerPolicy, template, csr.PublicKey, cfg.CertFilePath); err != nil {
+		return nil, err
+	}
+	return signTemplate(template, csr.PublicKey, parentCert, parentKey, cfg.StrictValidity, cfg.CertFilePath)
+}
@@ -0,0 +1,164 @@
+package certgo
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+var testCaseSignCsr = []struct {
+	name       string
+	commonName string
+	dnsNames   []string
+	uris       []*url.URL
+	emails     []string
+	issuer     string
+	expect     string
+}{
+	{
+		name:     "issuer of the profile",
+		dnsNames: []string{"ext.example.com"},
+	},
+	{
+		name:     "named issuer",
+		dnsNames: []string{"ext.example.com", "*.ext.example.com"},
+		issuer:   "intermediate",
+	},
+	{
+		name:     "rejected by the policy",
+		dnsNames: []string{"ext.example.com", "ext.other.test"},
+		issuer:   "intermediate",
+		expect:   `dns name "ext.other.test" is not under example.com`,
+	},
+	{
+		name:     "uri rejected by the policy",
+		dnsNames: []string{"ext.example.com"},
+		uris:     []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/ns/prod/sa/ext"}},
+		expect:   `uri "spiffe://example.com/ns/prod/sa/ext" is not allowed, the policy has no uri_prefixes`,
+	},
+	{
+		name:     "email address rejected by the policy",
+		dnsNames: []string{"ext.example.com"},
+		emails:   []string{"admin@example.com"},
+		expect:   `email address "admin@example.com" is not allowed, the policy has no email_domains`,
+	},
+	{
+		name:       "common name rejected by the policy",
+		commonName: "www.other.test",
+		dnsNames:   []string{"ext.example.com"},
+		expect:     `common name "www.other.test" is not under example.com`,
+	},
+	{
+		name:     "profile as its own issuer",
+		dnsNames: []string{"ext.example.com"},
+		issuer:   "web",
+		expect:   "profile web cannot be its own issuer",
+	},
+	{
+		name:     "unknown issuer",
+		dnsNames: []string{"ext.example.com"},
+		issuer:   "partner",
+		expect:   "issuer profile not found: partner",
+	},
+}
+
+func TestSignCsr(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "cfg.yml")
+
+	cfg, err := NewStarterConfig(StarterConfig{
+		Organization: "sign",
+		OutDir:       "./ca",
+		DNSNames:     []string{"web.example.com"},
+		Leaves:       []StarterLeaf{{Name: "web", Type: constants.CERT_TYPE_SERVER}},
+	})
+	if err != nil {
+		t.Fatalf("TestSignCsr: %v", err)
+	}
+	cfg.CA.Intermediate.Policy = &model.Policy{DNSSuffixes: []string{"example.com"}, IPRanges: []string{"10.0.0.0/8"}, RequiredSubject: []string{"common_name"}}
+	if err := util.WriteStructToYamlFile(yamlPath, cfg); err != nil {
+		t.Fatalf("TestSignCsr: %v", err)
+	}
+	if _, err := InitPKI(yamlPath, ""); err != nil {
+		t.Fatalf("TestSignCsr: %v", err)
+	}
+	intermediate, err := util.ReadCertificate(filepath.Join(dir, "ca", "intermediate", "intermediate.cert.pem"))
+	if err != nil {
+		t.Fatalf("TestSignCsr: %v", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("TestSignCsr: %v", err)
+	}
+	subject := pkix.Name{CommonName: "ext.example.com", Organization: []string{"other team"}, OrganizationalUnit: []string{"payments"}}
+
+	for i, testCase := range testCaseSignCsr {
+		t.Run(testCase.name, func(t *testing.T) {
+			csrSubject := subject
+			if testCase.commonName != "" {
+				csrSubject.CommonName = testCase.commonName
+			}
+			csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+				Subject:        csrSubject,
+				DNSNames:       testCase.dnsNames,
+				IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+				URIs:           testCase.uris,
+				EmailAddresses: testCase.emails,
+			}, key)
+			if err != nil {
+				t.Fatalf("TestSignCsr (%s): %v", testCase.name, err)
+			}
+			csrPath := filepath.Join(dir, "request.pem")
+			if err := os.WriteFile(csrPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes}), 0644); err != nil {
+				t.Fatalf("TestSignCsr (%s): %v", testCase.name, err)
+			}
+			certPath := filepath.Join(dir, "out", string(rune('a'+i))+".cert.pem")
+
+			cert, err := SignCsr(csrPath, certPath, testCase.issuer, "web", yamlPath, false)
+			if testCase.expect != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expect) {
+					t.Fatalf("TestSignCsr (%s): actual error %v, expect %s", testCase.name, err, testCase.expect)
+				}
+				if util.FileExists(certPath) {
+					t.Fatalf("TestSignCsr (%s): rejected certificate was written", testCase.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TestSignCsr (%s): %v", testCase.name, err)
+			}
+
+			if cert.Subject.String() != subject.String() || !reflect.DeepEqual(cert.DNSNames, testCase.dnsNames) || len(cert.IPAddresses) != 1 {
+				t.Fatalf("TestSignCsr (%s): identity not taken from the csr: %s %v %v", testCase.name, cert.Subject, cert.DNSNames, cert.IPAddresses)
+			}
+			if !reflect.DeepEqual(cert.ExtKeyUsage, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}) || cert.IsCA {
+				t.Fatalf("TestSignCsr (%s): usages not taken from the profile", testCase.name)
+			}
+			if err := cert.CheckSignatureFrom(intermediate); err != nil {
+				t.Fatalf("TestSignCsr (%s): %v", testCase.name, err)
+			}
+			written, err := util.ReadCertificate(certPath)
+			if err != nil || !written.Equal(cert) {
+				t.Fatalf("TestSignCsr (%s): certificate was not written", testCase.name)
+			}
+			if _, err := SignCsr(csrPath, certPath, testCase.issuer, "web", yamlPath, false); err == nil || err.Error() != "certificate already exists" {
+				t.Fatalf("TestSignCsr (%s): expected error for existing certificate without force", testCase.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+// Package tlsconfig builds *tls.Config values from cert-go's file layout and
+// swaps in renewed certificates without restarting the process.
+//
+//	r, err := tlsconfig.NewReloader(tlsconfig.FromCertificate(cfg.CA.Server, rootCertPath))
+//	go r.Watch(ctx, time.Minute)
+//	srv := &http.Server{TLSConfig: r.ServerConfig()}
+//
+// Certificates and trusted roots are read through the hooks of tls.Config on
+// every handshake, established connections are not affected by a reload.
+package tlsconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+// Files lists the files a Reloader loads.
+type Files struct {
+	// CertFile and KeyFile are the certificate and private key presented to the peer.
+	CertFile string
+	KeyFile  string
+	// ChainFiles are the issuing certificates sent after the certificate, self-signed ones are skipped.
+	ChainFiles []string
+	// RootFiles are the certificates trusted when verifying the peer.
+	RootFiles []string
+}
+
+// FromCertificate returns the files of a certificate configured in cert-go's YAML,
+// its parent certificate is used as chain.
+func FromCertificate(cfg model.Certificate, rootFiles ...string) Files {
+	files := Files{
+		CertFile:  cfg.CertFilePath,
+		KeyFile:   cfg.KeyFilePath,
+		RootFiles: rootFiles,
+	}
+	if cfg.ParentCertPath != "" {
+		files.ChainFiles = []string{cfg.ParentCertPath}
+	}
+	return files
+}
+
+type state struct {
+	cert  *tls.Certificate
+	roots *x509.CertPool
+	stamp string
+}
+
+// Reloader holds the certificate and trusted roots loaded from Files and reloads them when they change.
+type Reloader struct {
+	files Files
+	state atomic.Pointer[state]
+	mu    sync.Mutex
+}
+
+// NewReloader loads the files once, it fails if they do not form a valid certificate and key pair.
+func NewReloader(files Files) (*Reloader, error) {
+	if files.CertFile == "" || files.KeyFile == "" {
+		return nil, errors.New("certificate and private key files are required")
+	}
+
+	r := &Reloader{files: files}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the files again if any of them changed since the last load and
+// swaps in the new certificate. It reports whether a new certificate was loaded.
+// On error the previous certificate is kept.
+func (r *Reloader) Reload() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stamp, err := r.stamp()
+	if err != nil {
+		return false, err
+	}
+	if current := r.state.Load(); current != nil && current.stamp == stamp {
+		return false, nil
+	}
+
+	next, err := r.load()
+	if err != nil {
+		util.Logger().Error("failed to reload certificate", "path", r.files.CertFile, "error", err)
+		return false, err
+	}
+	next.stamp = stamp
+	r.state.Store(next)
+
+	util.Logger().Info("certificate loaded",
+		"serial", next.cert.Leaf.SerialNumber.Text(16),
+		"subject", next.cert.Leaf.Subject.String(),
+		"not_after", next.cert.Leaf.NotAfter,
+		"path", r.files.CertFile,
+	)
+	return true, nil
+}
+
+// Watch calls Reload every interval until ctx is done.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = r.Reload()
+		}
+	}
+}
+
+// Certificate returns the current certificate.
+func (r *Reloader) Certificate() *tls.Certificate {
+	return r.state.Load().cert
+}
+
+// ServerConfig returns a server config presenting the current certificate.
+// When root files are set, client certificates are required and verified against them.
+func (r *Reloader) ServerConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.Certificate(), nil
+		},
+	}
+	if len(r.files.RootFiles) != 0 {
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return r.verify(cs.PeerCertificates, "", x509.ExtKeyUsageClientAuth)
+		}
+	}
+	return cfg
+}
+
+// ClientConfig returns a client config presenting the current certificate and verifying
+// the server against the root files. serverName is the name checked in the server certificate,
+// when empty the host dialed by tls.Dial is checked and a connection without server name fails.
+func (r *Reloader) ClientConfig(serverName string) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return r.Certificate(), nil
+		},
+	}
+	if len(r.files.RootFiles) != 0 {
+		// the verification is done against the reloaded roots in VerifyConnection
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if cs.ServerName == "" {
+				return errors.New("no server name to verify the server certificate against")
+			}
+			return r.verify(cs.PeerCertificates, cs.ServerName, x509.ExtKeyUsageServerAuth)
+		}
+	}
+	return cfg
+}
+
+func (r *Reloader) verify(certs []*x509.Certificate, dnsName string, usage x509.ExtKeyUsage) error {
+	if len(certs) == 0 {
+		return errors.New("no peer certificate")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       dnsName,
+		Roots:         r.state.Load().roots,
+		Intermediates: intermediates,
+		CurrentTime:   util.Now(),
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+	return err
+}
+
+func (r *Reloader) paths() []string {
+	paths := []string{r.files.CertFile, r.files.KeyFile}
+	paths = append(paths, r.files.ChainFiles...)
+	return append(paths, r.files.RootFiles...)
+}
+
+// stamp hashes the content of every file, the files are small and modification
+// times are too coarse to notice a renewal written right after the previous one.
+func (r *Reloader) stamp() (string, error) {
+	h := sha256.New()
+	for _, path := range r.paths() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s:%d:", path, len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (r *Reloader) load() (*state, error) {
+	certPEM, err := os.ReadFile(r.files.CertFile)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(r.files.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range r.files.ChainFiles {
+		chain, err := readCertificates(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range chain {
+			if bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil {
+				continue
+			}
+			certPEM = append(certPEM, util.EncodeCertificatePEM(cert)...)
+		}
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	roots := x509.NewCertPool()
+	for _, path := range r.files.RootFiles {
+		certs, err := readCertificates(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range certs {
+			roots.AddCert(c)
+		}
+	}
+
+	return &state{cert: &cert, roots: roots}, nil
+}
+
+func readCertificates(path string) ([]*x509.Certificate, error) {
+	certs, err := util.ReadCertificates(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found in %s", path)
+	}
+	return certs, nil
+}
@@ -0,0 +1,124 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	certgo "github.com/Alonza0314/cert-go"
+	"github.com/Alonza0314/cert-go/certgotest"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+)
+
+func TestReloader(t *testing.T) {
+	pki := certgotest.New(t)
+	files := pki.WriteFiles(t)
+
+	server, err := NewReloader(FromCertificate(files.Server, files.Root.CertFilePath))
+	if err != nil {
+		t.Fatalf("TestReloader: %v", err)
+	}
+	client, err := NewReloader(FromCertificate(files.Client, files.Root.CertFilePath))
+	if err != nil {
+		t.Fatalf("TestReloader: %v", err)
+	}
+	if len(server.Certificate().Certificate) != 2 {
+		t.Fatalf("TestReloader: chain should hold the intermediate certificate only")
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = server.ServerConfig()
+	srv.StartTLS()
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	serial := func() string {
+		conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), client.ClientConfig("localhost"))
+		if err != nil {
+			t.Fatalf("TestReloader: %v", err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.String()
+	}
+
+	before := serial()
+	if before != pki.Server.Certificate.SerialNumber.String() {
+		t.Fatalf("TestReloader: unexpected server certificate")
+	}
+
+	if reloaded, err := server.Reload(); err != nil || reloaded {
+		t.Fatalf("TestReloader: unchanged files should not be reloaded: %v", err)
+	}
+
+	// renew the server certificate with the same key
+	renewed, err := certgo.IssueCertificate(model.Certificate{
+		CommonName:  "localhost",
+		DNSNames:    []string{"localhost"},
+		ValidityDay: 1,
+	}, pki.Server.PrivateKey.Public(), pki.Intermediate.Certificate, pki.Intermediate.PrivateKey)
+	if err != nil {
+		t.Fatalf("TestReloader: %v", err)
+	}
+	if err := util.FileWrite(files.Server.CertFilePath, util.EncodeCertificatePEM(renewed), 0644); err != nil {
+		t.Fatalf("TestReloader: %v", err)
+	}
+	if reloaded, err := server.Reload(); err != nil || !reloaded {
+		t.Fatalf("TestReloader: renewed certificate should be reloaded: %v", err)
+	}
+	if after := serial(); after != renewed.SerialNumber.String() {
+		t.Fatalf("TestReloader: renewed certificate is not served")
+	}
+
+	// a broken file keeps the previous certificate
+	if err := util.FileWrite(files.Server.CertFilePath, []byte("broken"), 0644); err != nil {
+		t.Fatalf("TestReloader: %v", err)
+	}
+	if _, err := server.Reload(); err == nil {
+		t.Fatalf("TestReloader: broken certificate should not be loaded")
+	}
+	if after := serial(); after != renewed.SerialNumber.String() {
+		t.Fatalf("TestReloader: previous certificate should be kept")
+	}
+
+	// the client rejects a server certificate not valid for the server name
+	if conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), client.ClientConfig("other.example")); err == nil {
+		conn.Close()
+		t.Fatalf("TestReloader: server certificate should not be valid for other.example")
+	}
+
+	// without server name the dialed host is checked
+	if conn, err := tls.Dial("tcp", "localhost:"+port, client.ClientConfig("")); err != nil {
+		t.Fatalf("TestReloader: %v", err)
+	} else {
+		conn.Close()
+	}
+	raw, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("TestReloader: %v", err)
+	}
+	conn := tls.Client(raw, client.ClientConfig(""))
+	if err := conn.Handshake(); err == nil {
+		t.Fatalf("TestReloader: connection without server name should fail")
+	}
+	conn.Close()
+
+	// the server rejects clients of another PKI
+	other := certgotest.New(t)
+	conn, err = tls.Dial("tcp", srv.Listener.Addr().String(), &tls.Config{
+		Certificates: []tls.Certificate{other.Client.TLSCertificate()},
+		RootCAs:      pki.CertPool(),
+	})
+	if err == nil {
+		_, err = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+	if err == nil {
+		t.Fatalf("TestReloader: client of another PKI should be rejected")
+	}
+}
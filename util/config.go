@@ -0,0 +1,141 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+var configFormat atomic.Value
+
+func init() {
+	SetConfigFormat("")
+}
+
+// SetConfigFormat forces the format of the configuration files. An empty format
+// restores the detection from the file extension.
+func SetConfigFormat(format constants.ConfigFormat) {
+	configFormat.Store(format)
+}
+
+// ConfigFormat returns the format of the configuration file at filePath: the format
+// set by SetConfigFormat, else json for .json, toml for .toml and yaml otherwise.
+func ConfigFormat(filePath string) constants.ConfigFormat {
+	if format := configFormat.Load().(constants.ConfigFormat); format != "" {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		return constants.CONFIG_FORMAT_JSON
+	case ".toml":
+		return constants.CONFIG_FORMAT_TOML
+	}
+	return constants.CONFIG_FORMAT_YAML
+}
+
+// ReadConfigFileToStruct strictly decodes the configuration file at filePath into v, the yaml
+// tags of v define the schema of every format. An empty format is detected by ConfigFormat.
+func ReadConfigFileToStruct(filePath string, format constants.ConfigFormat, v interface{}) error {
+	if format == "" {
+		format = ConfigFormat(filePath)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		Logger().Error("failed to read config file", "path", filePath, "error", err)
+		return err
+	}
+
+	if data, err = ConfigToYaml(data, format); err != nil {
+		Logger().Error("failed to parse config file", "path", filePath, "format", format, "error", err)
+		return err
+	}
+	if err := UnmarshalYamlStrict(data, v); err != nil {
+		Logger().Error("failed to unmarshal config file", "path", filePath, "format", format, "error", err)
+		return err
+	}
+	return nil
+}
+
+// WriteStructToConfigFile encodes v to the configuration file at filePath. An empty format is detected by ConfigFormat.
+func WriteStructToConfigFile(filePath string, format constants.ConfigFormat, v interface{}) error {
+	if format == "" {
+		format = ConfigFormat(filePath)
+	}
+
+	data, err := MarshalConfig(v, format)
+	if err != nil {
+		Logger().Error("failed to marshal config file", "path", filePath, "format", format, "error", err)
+		return err
+	}
+
+	if !FileDirExists(filePath) {
+		if err := FileDirCreate(filePath); err != nil {
+			return err
+		}
+	}
+	return FileWrite(filePath, data, 0644)
+}
+
+// ConfigToYaml converts a configuration of the given format to yaml. Json is valid yaml, it is
+// only checked and returned as is so that the lines of yaml errors match the json document.
+func ConfigToYaml(data []byte, format constants.ConfigFormat) ([]byte, error) {
+	switch format {
+	case constants.CONFIG_FORMAT_YAML:
+		return data, nil
+	case constants.CONFIG_FORMAT_JSON:
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return data, nil
+	case constants.CONFIG_FORMAT_TOML:
+		var v map[string]interface{}
+		if _, err := toml.Decode(string(data), &v); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(v)
+	}
+	return nil, fmt.Errorf("unsupported config format: %s", format)
+}
+
+// MarshalConfig encodes v in the given format, with the keys of its yaml tags.
+func MarshalConfig(v interface{}, format constants.ConfigFormat) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	if format == constants.CONFIG_FORMAT_YAML {
+		return buf.Bytes(), nil
+	}
+
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(buf.Bytes(), &m); err != nil {
+		return nil, err
+	}
+	switch format {
+	case constants.CONFIG_FORMAT_JSON:
+		data, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case constants.CONFIG_FORMAT_TOML:
+		buf.Reset()
+		if err := toml.NewEncoder(&buf).Encode(m); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported config format: %s", format)
+}
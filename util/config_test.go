@@ -0,0 +1,85 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+)
+
+var testCaseConfigFormat = []struct {
+	filePath string
+	expect   constants.ConfigFormat
+}{
+	{filePath: "cfg.yml", expect: constants.CONFIG_FORMAT_YAML},
+	{filePath: "cfg.yaml", expect: constants.CONFIG_FORMAT_YAML},
+	{filePath: "cfg", expect: constants.CONFIG_FORMAT_YAML},
+	{filePath: "./ca/cfg.JSON", expect: constants.CONFIG_FORMAT_JSON},
+	{filePath: "./ca/cfg.toml", expect: constants.CONFIG_FORMAT_TOML},
+}
+
+func TestConfigFormat(t *testing.T) {
+	for _, testCase := range testCaseConfigFormat {
+		if actual := ConfigFormat(testCase.filePath); actual != testCase.expect {
+			t.Errorf("TestConfigFormat (%s): actual %s != expect %s", testCase.filePath, actual, testCase.expect)
+		}
+	}
+
+	SetConfigFormat(constants.CONFIG_FORMAT_TOML)
+	defer SetConfigFormat("")
+	if actual := ConfigFormat("cfg.json"); actual != constants.CONFIG_FORMAT_TOML {
+		t.Errorf("TestConfigFormat: forced format is not used, got %s", actual)
+	}
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	var cfg model.CAConfig
+	if err := ReadYamlFileToStruct("../defaultCfg.yml", &cfg); err != nil {
+		t.Fatalf("TestConfigRoundTrip: %v", err)
+	}
+	// empty lists are omitted by every format, compare with the yaml round trip
+	yamlData, err := MarshalConfig(cfg, constants.CONFIG_FORMAT_YAML)
+	if err != nil {
+		t.Fatalf("TestConfigRoundTrip: %v", err)
+	}
+	var expect model.CAConfig
+	if err := UnmarshalYamlStrict(yamlData, &expect); err != nil {
+		t.Fatalf("TestConfigRoundTrip: %v", err)
+	}
+
+	for _, format := range []constants.ConfigFormat{constants.CONFIG_FORMAT_JSON, constants.CONFIG_FORMAT_TOML} {
+		t.Run(string(format), func(t *testing.T) {
+			data, err := MarshalConfig(expect, format)
+			if err != nil {
+				t.Fatalf("TestConfigRoundTrip (%s): %v", format, err)
+			}
+			if data, err = ConfigToYaml(data, format); err != nil {
+				t.Fatalf("TestConfigRoundTrip (%s): %v", format, err)
+			}
+			var actual model.CAConfig
+			if err := UnmarshalYamlStrict(data, &actual); err != nil {
+				t.Fatalf("TestConfigRoundTrip (%s): %v", format, err)
+			}
+			if !reflect.DeepEqual(actual, expect) {
+				t.Fatalf("TestConfigRoundTrip (%s): actual %v != expect %v", format, actual, expect)
+			}
+		})
+	}
+}
+
+func TestConfigUnknownKey(t *testing.T) {
+	for format, data := range map[constants.ConfigFormat]string{
+		constants.CONFIG_FORMAT_JSON: `{"ca": {"root": {"validity_year": 10}}}`,
+		constants.CONFIG_FORMAT_TOML: "[ca.root]\nvalidity_year = 10\n",
+	} {
+		yamlData, err := ConfigToYaml([]byte(data), format)
+		if err != nil {
+			t.Fatalf("TestConfigUnknownKey (%s): %v", format, err)
+		}
+		var cfg model.CAConfig
+		if err := UnmarshalYamlStrict(yamlData, &cfg); err == nil {
+			t.Fatalf("TestConfigUnknownKey (%s): expected error for unknown key", format)
+		}
+	}
+}
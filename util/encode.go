@@ -0,0 +1,38 @@
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+)
+
+func EncodePrivateKeyPEM(privateKey interface{}) ([]byte, error) {
+	var keyBytes []byte
+	switch key := privateKey.(type) {
+	case *ecdsa.PrivateKey:
+		var err error
+		keyBytes, err = x509.MarshalECPrivateKey(key)
+		if err != nil {
+			Logger().Error("failed to marshal private key", "error", err)
+			return nil, err
+		}
+	case *rsa.PrivateKey:
+		keyBytes = x509.MarshalPKCS1PrivateKey(key)
+	default:
+		return nil, fmt.Errorf("unsupported private key type: %T", privateKey)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  string(GetPrivateKeyType(privateKey)),
+		Bytes: keyBytes,
+	}), nil
+}
+
+func EncodeCertificatePEM(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: cert.Raw,
+	})
+}
@@ -0,0 +1,15 @@
+//go:build !unix && !windows
+
+package util
+
+import "os"
+
+// FileLock is a no-op on platforms without file locks, a file must then have a single writer.
+func FileLock(f *os.File) error {
+	return nil
+}
+
+// FileUnlock is a no-op on platforms without file locks.
+func FileUnlock(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package util
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// FileLock takes an exclusive lock on f, shared with other processes, waiting until it is free.
+func FileLock(f *os.File) error {
+	for {
+		err := unix.Flock(int(f.Fd()), unix.LOCK_EX)
+		if err != unix.EINTR {
+			return err
+		}
+	}
+}
+
+// FileUnlock releases the lock taken by FileLock, closing f releases it too.
+func FileUnlock(f *os.File) error {
+	return unix.Flock(int(f.Fd()), unix.LOCK_UN)
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package util
+
+import (
+	"math"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// FileLock takes an exclusive lock on f, shared with other processes, waiting until it is free.
+func FileLock(f *os.File) error {
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, math.MaxUint32, math.MaxUint32, new(windows.Overlapped))
+}
+
+// FileUnlock releases the lock taken by FileLock, closing f releases it too.
+func FileUnlock(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, math.MaxUint32, math.MaxUint32, new(windows.Overlapped))
+}
@@ -1,12 +1,12 @@
 package util
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
-
-	logger "github.com/Alonza0314/logger-go"
+	"sync/atomic"
 )
 
 func FileExists(filePath string) bool {
@@ -17,7 +17,7 @@ func FileExists(filePath string) bool {
 func FileWrite(filePath string, data []byte, code fs.FileMode) error {
 	err := os.WriteFile(filePath, data, code)
 	if err != nil {
-		logger.Error("FileWrite", fmt.Sprintf("%s, file path: %s", err.Error(), filePath))
+		Logger().Error("failed to write file", "path", filePath, "error", err)
 	}
 	return err
 }
@@ -25,7 +25,7 @@ func FileWrite(filePath string, data []byte, code fs.FileMode) error {
 func FileDelete(filePath string) error {
 	err := os.Remove(filePath)
 	if err != nil {
-		logger.Error("FileDelete", fmt.Sprintf("%s, file path: %s", err.Error(), filePath))
+		Logger().Error("failed to delete file", "path", filePath, "error", err)
 	}
 	return err
 }
@@ -37,7 +37,7 @@ func FileDir(filePath string) string {
 func FileDirCreate(filePath string) error {
 	err := os.MkdirAll(filepath.Dir(filePath), 0775)
 	if err != nil {
-		logger.Error("FileDirCreate", fmt.Sprintf("%s, file directory path: %s", err.Error(), filepath.Dir(filePath)))
+		Logger().Error("failed to create directory", "path", filepath.Dir(filePath), "error", err)
 	}
 	return err
 }
@@ -49,3 +49,129 @@ func FileDirExists(filePath string) bool {
 	}
 	return true
 }
+
+// BackupExtension ends the names of the backups kept by FileWriteAtomic.
+const BackupExtension = ".bak"
+
+var backup atomic.Bool
+
+// SetBackup keeps the previous content of the files replaced by FileWriteAtomic as timestamped backups.
+func SetBackup(enabled bool) {
+	backup.Store(enabled)
+}
+
+// FileWriteAtomic writes data to a temporary file in the directory of filePath, syncs it and renames it
+// to filePath, so filePath holds either its previous content or data, never a partial write.
+// When backups are enabled, an existing filePath is kept as filePath.<timestamp>.bak first.
+func FileWriteAtomic(filePath string, data []byte, code fs.FileMode) error {
+	staged, err := FileStage(filePath, data, code)
+	if err != nil {
+		return err
+	}
+	return FileCommit(staged, filePath)
+}
+
+// FileStage writes data to a synced temporary file in the directory of filePath and returns its path,
+// FileCommit moves it to filePath. A staged file that is not committed must be removed.
+func FileStage(filePath string, data []byte, code fs.FileMode) (string, error) {
+	staged, err := fileStage(filePath, data, code)
+	if err != nil {
+		Logger().Error("failed to write file", "path", filePath, "error", err)
+	}
+	return staged, err
+}
+
+// FileCommit renames staged, a file returned by FileStage, to filePath. When backups are enabled,
+// an existing filePath is kept as filePath.<timestamp>.bak first. staged is removed on error.
+func FileCommit(staged, filePath string) error {
+	if backup.Load() && FileExists(filePath) {
+		backupPath, err := fileBackup(filePath)
+		if err != nil {
+			_ = os.Remove(staged)
+			Logger().Error("failed to back up file", "path", filePath, "error", err)
+			return err
+		}
+		Logger().Info("file backed up", "path", filePath, "backup", backupPath)
+	}
+
+	if err := os.Rename(staged, filePath); err != nil {
+		_ = os.Remove(staged)
+		Logger().Error("failed to write file", "path", filePath, "error", err)
+		return err
+	}
+
+	// persist the rename itself, not supported on every platform
+	if dir, err := os.Open(filepath.Dir(filePath)); err == nil {
+		_ = dir.Sync()
+		_ = dir.Close()
+	}
+	return nil
+}
+
+// fileBackup links, or copies when links are not supported, filePath to a new timestamped backup.
+func fileBackup(filePath string) (string, error) {
+	base := filePath + "." + Now().UTC().Format("20060102T150405Z")
+	for i := 0; ; i++ {
+		backupPath := base + BackupExtension
+		if i > 0 {
+			backupPath = fmt.Sprintf("%s-%d%s", base, i, BackupExtension)
+		}
+		err := os.Link(filePath, backupPath)
+		if err == nil {
+			return backupPath, nil
+		}
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		return backupPath, fileCopy(filePath, backupPath)
+	}
+}
+
+func fileCopy(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func fileStage(filePath string, data []byte, code fs.FileMode) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+
+	err = func() error {
+		if _, err := tmp.Write(data); err != nil {
+			return err
+		}
+		if err := tmp.Chmod(code); err != nil {
+			return err
+		}
+		return tmp.Sync()
+	}()
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
@@ -0,0 +1,81 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "cert.pem")
+
+	if err := FileWriteAtomic(filePath, []byte("first"), 0644); err != nil {
+		t.Fatalf("TestFileWriteAtomic: %v", err)
+	}
+
+	SetBackup(true)
+	defer SetBackup(false)
+	SetClock(FixedClock(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)))
+	defer SetClock(nil)
+	for _, data := range []string{"second", "third"} {
+		if err := FileWriteAtomic(filePath, []byte(data), 0644); err != nil {
+			t.Fatalf("TestFileWriteAtomic: %v", err)
+		}
+	}
+
+	for path, expect := range map[string]string{
+		filePath:                             "third",
+		filePath + ".20240101T000000Z.bak":   "first",
+		filePath + ".20240101T000000Z-1.bak": "second",
+	} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("TestFileWriteAtomic: %v", err)
+		}
+		if string(data) != expect {
+			t.Fatalf("TestFileWriteAtomic (%s): actual %q != expect %q", path, data, expect)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("TestFileWriteAtomic: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Fatalf("TestFileWriteAtomic: temporary file %s left behind", entry.Name())
+		}
+	}
+
+	if err := FileWriteAtomic(filepath.Join(dir, "missing", "cert.pem"), []byte("data"), 0644); err == nil {
+		t.Fatalf("TestFileWriteAtomic: expected error for a missing directory")
+	}
+}
+
+func TestFileStage(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "key.pem")
+	if err := FileWriteAtomic(filePath, []byte("current"), 0600); err != nil {
+		t.Fatalf("TestFileStage: %v", err)
+	}
+
+	staged, err := FileStage(filePath, []byte("next"), 0600)
+	if err != nil {
+		t.Fatalf("TestFileStage: %v", err)
+	}
+	if data, err := os.ReadFile(filePath); err != nil || string(data) != "current" {
+		t.Fatalf("TestFileStage: staged data should not be visible before the commit: %q %v", data, err)
+	}
+	if err := FileCommit(staged, filePath); err != nil {
+		t.Fatalf("TestFileStage: %v", err)
+	}
+	if data, err := os.ReadFile(filePath); err != nil || string(data) != "next" {
+		t.Fatalf("TestFileStage: actual %q != expect %q: %v", data, "next", err)
+	}
+	if FileExists(staged) {
+		t.Fatalf("TestFileStage: staged file %s left behind", staged)
+	}
+}
@@ -0,0 +1,133 @@
+package util
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+)
+
+const (
+	// DefaultKeyFileMode is the permission of the private keys written by cert-go unless SetKeyFileMode is called.
+	DefaultKeyFileMode fs.FileMode = 0600
+	// KeyDirMode is the permission of the directories created for private keys.
+	KeyDirMode fs.FileMode = 0700
+)
+
+var (
+	keyFileMode    atomic.Uint32
+	keyFileUID     atomic.Int64
+	keyFileGID     atomic.Int64
+	strictKeyFiles atomic.Bool
+)
+
+func init() {
+	SetKeyFileMode(0)
+	keyFileUID.Store(-1)
+	keyFileGID.Store(-1)
+}
+
+// SetKeyFileMode sets the permission of the private keys written by cert-go, 0 restores DefaultKeyFileMode.
+func SetKeyFileMode(mode fs.FileMode) {
+	if mode == 0 {
+		mode = DefaultKeyFileMode
+	}
+	keyFileMode.Store(uint32(mode.Perm()))
+}
+
+// KeyFileMode returns the permission of the private keys written by cert-go.
+func KeyFileMode() fs.FileMode {
+	return fs.FileMode(keyFileMode.Load())
+}
+
+// SetKeyFileOwner sets the owner and group, names or numeric ids, given to the private keys written by
+// cert-go. An empty owner or group leaves it to the one of the process.
+func SetKeyFileOwner(owner, group string) error {
+	uid, gid := int64(-1), int64(-1)
+	if owner != "" {
+		id := owner
+		if u, err := user.Lookup(owner); err == nil {
+			id = u.Uid
+		}
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return fmt.Errorf("unknown user: %q", owner)
+		}
+		uid = n
+	}
+	if group != "" {
+		id := group
+		if g, err := user.LookupGroup(group); err == nil {
+			id = g.Gid
+		}
+		n, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return fmt.Errorf("unknown group: %q", group)
+		}
+		gid = n
+	}
+	keyFileUID.Store(uid)
+	keyFileGID.Store(gid)
+	return nil
+}
+
+// SetStrictKeyFiles makes ReadPrivateKey refuse private keys readable by the group or others
+// instead of only logging a warning.
+func SetStrictKeyFiles(strict bool) {
+	strictKeyFiles.Store(strict)
+}
+
+// WritePrivateKeyFile writes the private key atomically with the key file mode and owner, its
+// directory is created with KeyDirMode when missing.
+func WritePrivateKeyFile(keyPath string, keyPEM []byte) error {
+	staged, err := StagePrivateKeyFile(keyPath, keyPEM)
+	if err != nil {
+		return err
+	}
+	return FileCommit(staged, keyPath)
+}
+
+// StagePrivateKeyFile is WritePrivateKeyFile up to the rename, it returns the staged file to pass to FileCommit.
+func StagePrivateKeyFile(keyPath string, keyPEM []byte) (string, error) {
+	if !FileDirExists(keyPath) {
+		Logger().Warn("directory not exists, creating", "path", FileDir(keyPath))
+		if err := os.MkdirAll(filepath.Dir(keyPath), KeyDirMode); err != nil {
+			Logger().Error("failed to create directory", "path", FileDir(keyPath), "error", err)
+			return "", err
+		}
+		Logger().Debug("directory created", "path", FileDir(keyPath))
+	}
+
+	staged, err := FileStage(keyPath, keyPEM, KeyFileMode())
+	if err != nil {
+		return "", err
+	}
+
+	uid, gid := keyFileUID.Load(), keyFileGID.Load()
+	if uid != -1 || gid != -1 {
+		if err := os.Chown(staged, int(uid), int(gid)); err != nil {
+			_ = os.Remove(staged)
+			Logger().Error("failed to change the owner of the private key", "path", keyPath, "error", err)
+			return "", err
+		}
+	}
+	return staged, nil
+}
+
+// checkKeyFile warns about, or refuses in strict mode, a private key the group or others can access
+// beyond what the key file mode allows.
+func checkKeyFile(keyPath string, info fs.FileInfo) error {
+	if runtime.GOOS == "windows" || info.Mode().Perm()&^KeyFileMode()&0077 == 0 {
+		return nil
+	}
+	if strictKeyFiles.Load() {
+		Logger().Error("private key is readable by other users", "path", keyPath, "mode", info.Mode().Perm().String())
+		return fmt.Errorf("private key %s is readable by other users, mode %s, run chmod 600", keyPath, info.Mode().Perm())
+	}
+	Logger().Warn("private key is readable by other users, run chmod 600", "path", keyPath, "mode", info.Mode().Perm().String())
+	return nil
+}
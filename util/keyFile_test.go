@@ -0,0 +1,73 @@
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWritePrivateKeyFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not supported")
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("TestWritePrivateKeyFile: %v", err)
+	}
+	keyPEM, err := EncodePrivateKeyPEM(key)
+	if err != nil {
+		t.Fatalf("TestWritePrivateKeyFile: %v", err)
+	}
+
+	keyPath := filepath.Join(t.TempDir(), "keys", "root.key.pem")
+	if err := WritePrivateKeyFile(keyPath, keyPEM); err != nil {
+		t.Fatalf("TestWritePrivateKeyFile: %v", err)
+	}
+	for path, expect := range map[string]os.FileMode{keyPath: DefaultKeyFileMode, filepath.Dir(keyPath): KeyDirMode} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("TestWritePrivateKeyFile: %v", err)
+		}
+		if info.Mode().Perm() != expect {
+			t.Fatalf("TestWritePrivateKeyFile (%s): actual mode %s != expect %s", path, info.Mode().Perm(), expect)
+		}
+	}
+
+	SetStrictKeyFiles(true)
+	defer SetStrictKeyFiles(false)
+	if _, err := ReadPrivateKey(keyPath); err != nil {
+		t.Fatalf("TestWritePrivateKeyFile: %v", err)
+	}
+	if err := os.Chmod(keyPath, 0644); err != nil {
+		t.Fatalf("TestWritePrivateKeyFile: %v", err)
+	}
+	if _, err := ReadPrivateKey(keyPath); err == nil {
+		t.Fatalf("TestWritePrivateKeyFile: expected error for a world readable key in strict mode")
+	}
+
+	// a group readable key is accepted when it is the configured mode
+	SetKeyFileMode(0640)
+	defer SetKeyFileMode(0)
+	if err := WritePrivateKeyFile(keyPath, keyPEM); err != nil {
+		t.Fatalf("TestWritePrivateKeyFile: %v", err)
+	}
+	if _, err := ReadPrivateKey(keyPath); err != nil {
+		t.Fatalf("TestWritePrivateKeyFile: %v", err)
+	}
+
+	SetStrictKeyFiles(false)
+	if err := os.Chmod(keyPath, 0644); err != nil {
+		t.Fatalf("TestWritePrivateKeyFile: %v", err)
+	}
+	if _, err := ReadPrivateKey(keyPath); err != nil {
+		t.Fatalf("TestWritePrivateKeyFile: world readable key must only be warned about: %v", err)
+	}
+
+	if err := SetKeyFileOwner("cert-go-no-such-user", ""); err == nil {
+		t.Fatalf("TestWritePrivateKeyFile: expected error for an unknown owner")
+	}
+}
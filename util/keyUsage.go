@@ -0,0 +1,132 @@
+package util
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var keyUsageNames = map[string]x509.KeyUsage{
+	"digitalSignature":  x509.KeyUsageDigitalSignature,
+	"contentCommitment": x509.KeyUsageContentCommitment,
+	"nonRepudiation":    x509.KeyUsageContentCommitment,
+	"keyEncipherment":   x509.KeyUsageKeyEncipherment,
+	"dataEncipherment":  x509.KeyUsageDataEncipherment,
+	"keyAgreement":      x509.KeyUsageKeyAgreement,
+	"certSign":          x509.KeyUsageCertSign,
+	"keyCertSign":       x509.KeyUsageCertSign,
+	"crlSign":           x509.KeyUsageCRLSign,
+	"cRLSign":           x509.KeyUsageCRLSign,
+	"encipherOnly":      x509.KeyUsageEncipherOnly,
+	"decipherOnly":      x509.KeyUsageDecipherOnly,
+}
+
+var extKeyUsageNames = map[string]x509.ExtKeyUsage{
+	"any":             x509.ExtKeyUsageAny,
+	"serverAuth":      x509.ExtKeyUsageServerAuth,
+	"clientAuth":      x509.ExtKeyUsageClientAuth,
+	"codeSigning":     x509.ExtKeyUsageCodeSigning,
+	"emailProtection": x509.ExtKeyUsageEmailProtection,
+	"ipsecEndSystem":  x509.ExtKeyUsageIPSECEndSystem,
+	"ipsecTunnel":     x509.ExtKeyUsageIPSECTunnel,
+	"ipsecUser":       x509.ExtKeyUsageIPSECUser,
+	"timeStamping":    x509.ExtKeyUsageTimeStamping,
+	"OCSPSigning":     x509.ExtKeyUsageOCSPSigning,
+}
+
+// extKeyUsageOIDs are the object identifiers crypto/x509 has an ExtKeyUsage for.
+var extKeyUsageOIDs = []struct {
+	oid   asn1.ObjectIdentifier
+	usage x509.ExtKeyUsage
+}{
+	{asn1.ObjectIdentifier{2, 5, 29, 37, 0}, x509.ExtKeyUsageAny},
+	{asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 1}, x509.ExtKeyUsageServerAuth},
+	{asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 2}, x509.ExtKeyUsageClientAuth},
+	{asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 3}, x509.ExtKeyUsageCodeSigning},
+	{asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 4}, x509.ExtKeyUsageEmailProtection},
+	{asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 5}, x509.ExtKeyUsageIPSECEndSystem},
+	{asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 6}, x509.ExtKeyUsageIPSECTunnel},
+	{asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 7}, x509.ExtKeyUsageIPSECUser},
+	{asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 8}, x509.ExtKeyUsageTimeStamping},
+	{asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 9}, x509.ExtKeyUsageOCSPSigning},
+	{asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 10, 3, 3}, x509.ExtKeyUsageMicrosoftServerGatedCrypto},
+	{asn1.ObjectIdentifier{2, 16, 840, 1, 113730, 4, 1}, x509.ExtKeyUsageNetscapeServerGatedCrypto},
+	{asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 22}, x509.ExtKeyUsageMicrosoftCommercialCodeSigning},
+	{asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 61, 1, 1}, x509.ExtKeyUsageMicrosoftKernelCodeSigning},
+}
+
+func ParseKeyUsage(names []string) (x509.KeyUsage, error) {
+	var keyUsage x509.KeyUsage
+	for _, name := range names {
+		usage, ok := keyUsageNames[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown key usage: %s", name)
+		}
+		keyUsage |= usage
+	}
+	return keyUsage, nil
+}
+
+// ParseExtKeyUsage parses extended key usage names. Entries in dotted form, such as
+// 1.3.6.1.5.5.7.3.17, are returned as raw object identifiers unless crypto/x509 has
+// an ExtKeyUsage for them.
+func ParseExtKeyUsage(names []string) ([]x509.ExtKeyUsage, []asn1.ObjectIdentifier, error) {
+	extKeyUsage := make([]x509.ExtKeyUsage, 0, len(names))
+	var oids []asn1.ObjectIdentifier
+	for _, name := range names {
+		if usage, ok := extKeyUsageNames[name]; ok {
+			extKeyUsage = append(extKeyUsage, usage)
+			continue
+		}
+		oid, err := parseOID(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unknown extended key usage: %s", name)
+		}
+		if usage, ok := extKeyUsageFromOID(oid); ok {
+			extKeyUsage = append(extKeyUsage, usage)
+			continue
+		}
+		oids = append(oids, oid)
+	}
+	return extKeyUsage, oids, nil
+}
+
+func extKeyUsageFromOID(oid asn1.ObjectIdentifier) (x509.ExtKeyUsage, bool) {
+	for _, known := range extKeyUsageOIDs {
+		if known.oid.Equal(oid) {
+			return known.usage, true
+		}
+	}
+	return 0, false
+}
+
+func parseOID(s string) (asn1.ObjectIdentifier, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid object identifier: %s", s)
+	}
+	oid := make(asn1.ObjectIdentifier, 0, len(parts))
+	for _, part := range parts {
+		arc, err := strconv.Atoi(part)
+		if err != nil || arc < 0 {
+			return nil, fmt.Errorf("invalid object identifier: %s", s)
+		}
+		oid = append(oid, arc)
+	}
+	if oid[0] > 2 || (oid[0] < 2 && oid[1] > 39) {
+		return nil, fmt.Errorf("invalid object identifier: %s", s)
+	}
+	return oid, nil
+}
+
+// ExtKeyUsageName returns the name of usage, or its number when it has none.
+func ExtKeyUsageName(usage x509.ExtKeyUsage) string {
+	for name, u := range extKeyUsageNames {
+		if u == usage {
+			return name
+		}
+	}
+	return strconv.Itoa(int(usage))
+}
@@ -0,0 +1,114 @@
+package util
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"reflect"
+	"testing"
+)
+
+var testCaseKeyUsage = []struct {
+	name   string
+	names  []string
+	expect x509.KeyUsage
+	err    bool
+}{
+	{
+		name:   "empty",
+		names:  nil,
+		expect: 0,
+	},
+	{
+		name:   "digital signature and key encipherment",
+		names:  []string{"digitalSignature", "keyEncipherment"},
+		expect: x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	},
+	{
+		name:   "ca usages",
+		names:  []string{"keyCertSign", "cRLSign"},
+		expect: x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	},
+	{
+		name:  "unknown name",
+		names: []string{"digitalSignature", "signEverything"},
+		err:   true,
+	},
+}
+
+func TestParseKeyUsage(t *testing.T) {
+	for _, testCase := range testCaseKeyUsage {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, err := ParseKeyUsage(testCase.names)
+			if testCase.err {
+				if err == nil {
+					t.Fatalf("TestParseKeyUsage (%s): error should be raised", testCase.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TestParseKeyUsage (%s): %v", testCase.name, err)
+			}
+			if actual != testCase.expect {
+				t.Fatalf("TestParseKeyUsage (%s): actual %v != expect %v", testCase.name, actual, testCase.expect)
+			}
+		})
+	}
+}
+
+var testCaseExtKeyUsage = []struct {
+	name       string
+	names      []string
+	expect     []x509.ExtKeyUsage
+	expectOIDs []asn1.ObjectIdentifier
+	err        bool
+}{
+	{
+		name:   "named usages",
+		names:  []string{"serverAuth", "clientAuth", "codeSigning", "emailProtection", "timeStamping", "OCSPSigning"},
+		expect: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageCodeSigning, x509.ExtKeyUsageEmailProtection, x509.ExtKeyUsageTimeStamping, x509.ExtKeyUsageOCSPSigning},
+	},
+	{
+		name:       "raw oid",
+		names:      []string{"serverAuth", "1.3.6.1.5.5.7.3.17"},
+		expect:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		expectOIDs: []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 17}},
+	},
+	{
+		name:   "known oid",
+		names:  []string{"1.3.6.1.5.5.7.3.1", "1.3.6.1.5.5.7.3.2", "2.5.29.37.0"},
+		expect: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageAny},
+	},
+	{
+		name:  "invalid oid",
+		names: []string{"1.3.x"},
+		err:   true,
+	},
+	{
+		name:  "unknown name",
+		names: []string{"serverauth"},
+		err:   true,
+	},
+}
+
+func TestParseExtKeyUsage(t *testing.T) {
+	for _, testCase := range testCaseExtKeyUsage {
+		t.Run(testCase.name, func(t *testing.T) {
+			actual, oids, err := ParseExtKeyUsage(testCase.names)
+			if testCase.err {
+				if err == nil {
+					t.Fatalf("TestParseExtKeyUsage (%s): error should be raised", testCase.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TestParseExtKeyUsage (%s): %v", testCase.name, err)
+			}
+			if !reflect.DeepEqual(actual, testCase.expect) {
+				t.Fatalf("TestParseExtKeyUsage (%s): actual %v != expect %v", testCase.name, actual, testCase.expect)
+			}
+			if !reflect.DeepEqual(oids, testCase.expectOIDs) {
+				t.Fatalf("TestParseExtKeyUsage (%s): actual %v != expect %v", testCase.name, oids, testCase.expectOIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package util
+
+import (
+	"crypto/rand"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/Alonza0314/cert-go/internal/detrand"
+)
+
+// Clock reports the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// FixedClock is a Clock that always reports the same time.
+type FixedClock time.Time
+
+func (c FixedClock) Now() time.Time { return time.Time(c) }
+
+type randHolder struct{ r io.Reader }
+
+type clockHolder struct{ c Clock }
+
+var (
+	randReader atomic.Pointer[randHolder]
+	clock      atomic.Pointer[clockHolder]
+)
+
+func init() {
+	SetRandReader(nil)
+	SetClock(nil)
+}
+
+// SetRandReader sets the source of randomness passed to crypto/ecdsa, crypto/rsa and crypto/x509
+// for keys, serial numbers and signatures. A nil reader restores crypto/rand.Reader.
+// The standard library may still mix in system randomness, and any reader other than
+// crypto/rand.Reader must not be used for production keys.
+func SetRandReader(r io.Reader) {
+	if r == nil {
+		r = rand.Reader
+	}
+	randReader.Store(&randHolder{r: r})
+}
+
+// RandReader returns the source of randomness used by cert-go.
+func RandReader() io.Reader {
+	if r := detrand.Reader(); r != nil {
+		return r
+	}
+	return randReader.Load().r
+}
+
+// SetClock sets the clock used for validity windows. A nil clock restores the system clock.
+func SetClock(c Clock) {
+	if c == nil {
+		c = systemClock{}
+	}
+	clock.Store(&clockHolder{c: c})
+}
+
+// Now returns the current time of the clock used by cert-go.
+func Now() time.Time {
+	return clock.Load().c.Now()
+}
@@ -5,28 +5,58 @@ import (
 	"encoding/pem"
 	"errors"
 	"os"
-
-	logger "github.com/Alonza0314/logger-go"
 )
 
 func ReadCertificate(certPath string) (*x509.Certificate, error) {
-	certBytes, err := os.ReadFile(certPath)
+	certs, err := ReadCertificates(certPath)
 	if err != nil {
-		logger.Error("ReadCertificate", err.Error())
 		return nil, err
 	}
-
-	block, _ := pem.Decode(certBytes)
-	if block == nil {
-		logger.Error("ReadCertificate", "failed to decode PEM block")
+	if len(certs) == 0 {
+		Logger().Error("failed to decode PEM block", "path", certPath)
 		return nil, errors.New("failed to decode PEM block")
 	}
+	return certs[0], nil
+}
+
+// ReadCertificates returns every certificate of the PEM file, blocks of other types are skipped.
+func ReadCertificates(certPath string) ([]*x509.Certificate, error) {
+	certBytes, err := os.ReadFile(certPath)
+	if err != nil {
+		Logger().Error("failed to read certificate", "path", certPath, "error", err)
+		return nil, err
+	}
 
-	cert, err := x509.ParseCertificate(block.Bytes)
+	certs, err := ParseCertificates(certBytes, false)
 	if err != nil {
-		logger.Error("ReadCertificate", err.Error())
+		Logger().Error("failed to parse certificate", "path", certPath, "error", err)
 		return nil, err
 	}
+	return certs, nil
+}
 
-	return cert, nil
+// ParseCertificates returns the certificates of the PEM blocks of data, blocks of other types are skipped.
+// When der is set, data without any PEM block is parsed as DER certificates.
+func ParseCertificates(data []byte, der bool) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest, found := data, false
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		found = true
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if !found && der {
+		return x509.ParseCertificates(data)
+	}
+	return certs, nil
 }
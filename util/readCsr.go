@@ -5,36 +5,34 @@ import (
 	"encoding/pem"
 	"errors"
 	"os"
-
-	logger "github.com/Alonza0314/logger-go"
 )
 
 func ReadCsr(csrPath string) (*x509.CertificateRequest, error) {
 	csrPEM, err := os.ReadFile(csrPath)
 	if err != nil {
-		logger.Error("ReadCsr", err.Error())
+		Logger().Error("failed to read csr", "path", csrPath, "error", err)
 		return nil, err
 	}
 
 	block, _ := pem.Decode(csrPEM)
 	if block == nil {
-		logger.Error("ReadCsr", "failed to decode PEM block")
+		Logger().Error("failed to decode PEM block", "path", csrPath)
 		return nil, errors.New("failed to decode PEM block")
 	}
 
 	if block.Type != "CERTIFICATE REQUEST" {
-		logger.Error("ReadCsr", "invalid PEM type: "+block.Type)
+		Logger().Error("invalid PEM type", "path", csrPath, "type", block.Type)
 		return nil, errors.New("invalid PEM type")
 	}
 
 	csr, err := x509.ParseCertificateRequest(block.Bytes)
 	if err != nil {
-		logger.Error("ReadCsr", err.Error())
+		Logger().Error("failed to parse csr", "path", csrPath, "error", err)
 		return nil, err
 	}
 
 	if err := csr.CheckSignature(); err != nil {
-		logger.Error("ReadCsr", err.Error())
+		Logger().Error("invalid csr signature", "path", csrPath, "error", err)
 		return nil, err
 	}
 
@@ -10,19 +10,29 @@ import (
 	"os"
 
 	"github.com/Alonza0314/cert-go/constants"
-	logger "github.com/Alonza0314/logger-go"
 )
 
+// ReadPrivateKey reads the ECDSA or RSA private key at keyPath. A key readable by the group or others
+// is logged, or refused when SetStrictKeyFiles is enabled.
 func ReadPrivateKey(keyPath string) (interface{}, error) {
+	info, err := os.Stat(keyPath)
+	if err != nil {
+		Logger().Error("failed to read private key", "path", keyPath, "error", err)
+		return nil, err
+	}
+	if err := checkKeyFile(keyPath, info); err != nil {
+		return nil, err
+	}
+
 	keyPEM, err := os.ReadFile(keyPath)
 	if err != nil {
-		logger.Error("ReadPrivateKey", err.Error())
+		Logger().Error("failed to read private key", "path", keyPath, "error", err)
 		return nil, err
 	}
 
 	block, _ := pem.Decode(keyPEM)
 	if block == nil {
-		logger.Error("ReadPrivateKey", "failed to decode PEM block")
+		Logger().Error("failed to decode PEM block", "path", keyPath)
 		return nil, errors.New("failed to decode PEM block")
 	}
 
@@ -30,7 +40,7 @@ func ReadPrivateKey(keyPath string) (interface{}, error) {
 	case string(constants.PRIVATE_KEY_TYPE_ECDSA):
 		privateKey, err := x509.ParseECPrivateKey(block.Bytes)
 		if err != nil {
-			logger.Error("ReadPrivateKey", err.Error())
+			Logger().Error("failed to parse private key", "path", keyPath, "error", err)
 			return nil, err
 		}
 		return privateKey, nil
@@ -38,13 +48,13 @@ func ReadPrivateKey(keyPath string) (interface{}, error) {
 	case string(constants.PRIVATE_KEY_TYPE_RSA):
 		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
 		if err != nil {
-			logger.Error("ReadPrivateKey", err.Error())
+			Logger().Error("failed to parse private key", "path", keyPath, "error", err)
 			return nil, err
 		}
 		return privateKey, nil
 
 	default:
-		logger.Error("ReadPrivateKey", "unsupported private key type: "+block.Type)
+		Logger().Error("unsupported private key type", "path", keyPath, "type", block.Type)
 		return nil, fmt.Errorf("unsupported private key type: %s", block.Type)
 	}
 }
@@ -60,6 +70,17 @@ func GetPrivateKeyType(privateKey interface{}) constants.PrivateKeyType {
 	}
 }
 
+// ParsePrivateKeyType returns the private key type named ecdsa or rsa.
+func ParsePrivateKeyType(name string) (constants.PrivateKeyType, error) {
+	switch name {
+	case "ecdsa":
+		return constants.PRIVATE_KEY_TYPE_ECDSA, nil
+	case "rsa":
+		return constants.PRIVATE_KEY_TYPE_RSA, nil
+	}
+	return "", fmt.Errorf("unknown private key type: %q, expected ecdsa or rsa", name)
+}
+
 func IsPrivateKeyTypeSame(privateKey interface{}, keyType constants.PrivateKeyType) (bool, error) {
 	if GetPrivateKeyType(privateKey) != keyType {
 		if keyType == constants.PRIVATE_KEY_TYPE_RSA {
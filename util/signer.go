@@ -0,0 +1,12 @@
+package util
+
+import "github.com/Alonza0314/cert-go/internal/detrand"
+
+// Signer returns the key to sign with, the key itself unless the deterministic mode of
+// the tests is enabled.
+func Signer(key interface{}) interface{} {
+	if random := detrand.Reader(); random != nil {
+		return detrand.Signer(key, random)
+	}
+	return key
+}
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTimestamp parses an RFC 3339 timestamp, or a date alone which is midnight UTC.
+func ParseTimestamp(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp: %q, expected RFC 3339 like 2006-01-02T15:04:05Z or a date like 2006-01-02", value)
+}
+
+// ParseDuration parses a non negative Go duration like 2160h or 90m.
+func ParseDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: %q, expected a duration like 2160h or 90m", value)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid duration: %q, cannot be negative", value)
+	}
+	return d, nil
+}
+
+// ParseDayDuration parses a number of days like 30d, or a non negative Go duration like 720h.
+func ParseDayDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration: %q, expected a number of days like 30d or a duration like 720h", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return ParseDuration(value)
+}
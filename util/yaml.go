@@ -1,24 +1,29 @@
 package util
 
 import (
-	"os"
+	"bytes"
+	"errors"
+	"io"
 
-	logger "github.com/Alonza0314/logger-go"
+	"github.com/Alonza0314/cert-go/constants"
 	"gopkg.in/yaml.v3"
 )
 
 func ReadYamlFileToStruct(filePath string, v interface{}) error {
-	yamlFile, err := os.ReadFile(filePath)
-	if err != nil {
-		logger.Error("ReadYamlFile", err.Error())
-		return err
-	}
+	return ReadConfigFileToStruct(filePath, constants.CONFIG_FORMAT_YAML, v)
+}
 
-	err = yaml.Unmarshal(yamlFile, v)
-	if err != nil {
-		logger.Error("ReadYamlFileToStruct", err.Error())
+// UnmarshalYamlStrict decodes data into v and fails on keys v has no field for,
+// so a misspelled key is reported instead of leaving its field to the zero value.
+func UnmarshalYamlStrict(data []byte, v interface{}) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(v); err != nil && !errors.Is(err, io.EOF) {
 		return err
 	}
-
 	return nil
 }
+
+func WriteStructToYamlFile(filePath string, v interface{}) error {
+	return WriteStructToConfigFile(filePath, constants.CONFIG_FORMAT_YAML, v)
+}
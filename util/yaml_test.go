@@ -21,6 +21,7 @@ var testCaseStruct = []struct {
 					Type:          "root",
 					CertFilePath:  "./default_ca/root/root.cert.pem",
 					KeyFilePath:   "./default_ca/root/root.key.pem",
+					KeyType:       "ecdsa",
 					IsCA:          true,
 					Organization:  "default_ca",
 					CommonName:    "default_ca",
@@ -32,6 +33,7 @@ var testCaseStruct = []struct {
 					Type:           "intermediate",
 					CertFilePath:   "./default_ca/intermediate/intermediate.cert.pem",
 					KeyFilePath:    "./default_ca/intermediate/intermediate.key.pem",
+					KeyType:        "ecdsa",
 					CsrFilePath:    "./default_ca/intermediate/intermediate.csr.pem",
 					ParentCertPath: "./default_ca/root/root.cert.pem",
 					ParentKeyPath:  "./default_ca/root/root.key.pem",
@@ -46,6 +48,7 @@ var testCaseStruct = []struct {
 					Type:           "server",
 					CertFilePath:   "./default_ca/server/server.cert.pem",
 					KeyFilePath:    "./default_ca/server/server.key.pem",
+					KeyType:        "ecdsa",
 					CsrFilePath:    "./default_ca/server/server.csr.pem",
 					ParentCertPath: "./default_ca/intermediate/intermediate.cert.pem",
 					ParentKeyPath:  "./default_ca/intermediate/intermediate.key.pem",
@@ -63,6 +66,7 @@ var testCaseStruct = []struct {
 					Type:           "client",
 					CertFilePath:   "./default_ca/client/client.cert.pem",
 					KeyFilePath:    "./default_ca/client/client.key.pem",
+					KeyType:        "ecdsa",
 					CsrFilePath:    "./default_ca/client/client.csr.pem",
 					ParentCertPath: "./default_ca/intermediate/intermediate.cert.pem",
 					ParentKeyPath:  "./default_ca/intermediate/intermediate.key.pem",
@@ -76,6 +80,44 @@ var testCaseStruct = []struct {
 					IPAddresses:    []string{"127.0.0.1", "0.0.0.0"},
 					URIs:           []string{},
 				},
+				Peer: model.Certificate{
+					Type:           "peer",
+					CertFilePath:   "./default_ca/peer/peer.cert.pem",
+					KeyFilePath:    "./default_ca/peer/peer.key.pem",
+					KeyType:        "ecdsa",
+					CsrFilePath:    "./default_ca/peer/peer.csr.pem",
+					ParentCertPath: "./default_ca/intermediate/intermediate.cert.pem",
+					ParentKeyPath:  "./default_ca/intermediate/intermediate.key.pem",
+					IsCA:           false,
+					Organization:   "default_ca",
+					CommonName:     "default_ca",
+					ValidityYears:  10,
+					ValidityMonth:  0,
+					ValidityDay:    0,
+					DNSNames:       []string{"localhost"},
+					IPAddresses:    []string{"127.0.0.1", "0.0.0.0"},
+					URIs:           []string{},
+				},
+				Profiles: map[string]model.Certificate{
+					"api-gateway": {
+						CertFilePath:     "./default_ca/api-gateway/api-gateway.cert.pem",
+						KeyFilePath:      "./default_ca/api-gateway/api-gateway.key.pem",
+						KeyType:          "ecdsa",
+						CsrFilePath:      "./default_ca/api-gateway/api-gateway.csr.pem",
+						Issuer:           "intermediate",
+						IsCA:             false,
+						Organization:     "default_ca",
+						CommonName:       "api-gateway",
+						ValidityYears:    1,
+						ValidityMonth:    0,
+						ValidityDay:      0,
+						KeyUsageNames:    []string{"digitalSignature"},
+						ExtKeyUsageNames: []string{"serverAuth", "clientAuth"},
+						DNSNames:         []string{"localhost"},
+						IPAddresses:      []string{"127.0.0.1"},
+						URIs:             []string{},
+					},
+				},
 			},
 		},
 	},
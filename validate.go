@@ -0,0 +1,491 @@
+package certgo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Alonza0314/cert-go/constants"
+	"github.com/Alonza0314/cert-go/model"
+	"github.com/Alonza0314/cert-go/util"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigProblem is a problem found in a configuration by ValidateConfig.
+type ConfigProblem struct {
+	// Line is the line of the offending key in the yaml file, 0 when the key is missing from the file.
+	Line int
+	// Profile is the name of the slot or profile the problem belongs to, empty for the file itself.
+	Profile string
+	Message string
+}
+
+// ValidateConfigFile returns every problem of the configuration file at yamlPath. Json and toml
+// files are validated like yaml, toml problems have no line.
+// An error is returned only when the file cannot be read or cannot be parsed.
+func ValidateConfigFile(yamlPath string) ([]ConfigProblem, error) {
+	data, err := os.ReadFile(yamlPath)
+	if err != nil {
+		util.Logger().Error("failed to read config file", "path", yamlPath, "error", err)
+		return nil, err
+	}
+
+	format := util.ConfigFormat(yamlPath)
+	if data, err = util.ConfigToYaml(data, format); err != nil {
+		return nil, err
+	}
+	problems, err := ValidateConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	if format == constants.CONFIG_FORMAT_TOML {
+		// the lines are the ones of the yaml conversion
+		for i := range problems {
+			problems[i].Line = 0
+		}
+	}
+	return problems, nil
+}
+
+// ValidateConfig returns every problem of the configuration yaml or json data sorted by line: unknown keys,
+// unknown types and key types, certificates without validity, non-root certificates without issuer or
+// parent paths, is_ca inconsistent with the type, unknown key usages and malformed subject alternative names.
+// An error is returned only when data is not valid yaml.
+func ValidateConfig(data []byte) ([]ConfigProblem, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	var problems []ConfigProblem
+	var cfg model.CAConfig
+	if err := util.UnmarshalYamlStrict(data, &cfg); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, err
+		}
+		for _, msg := range typeErr.Errors {
+			problems = append(problems, decodeProblem(msg))
+		}
+	}
+
+	var root *yaml.Node
+	if len(doc.Content) != 0 {
+		root = doc.Content[0]
+	}
+	_, caNode := mappingValue(root, "ca")
+	if caNode == nil || caNode.Kind != yaml.MappingNode {
+		return append([]ConfigProblem{{Message: "missing ca section"}}, problems...), nil
+	}
+
+	for i := 0; i+1 < len(caNode.Content); i += 2 {
+		key, value := caNode.Content[i], caNode.Content[i+1]
+		if key.Value == "profiles" {
+			if value.Kind != yaml.MappingNode {
+				continue
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				name := value.Content[j].Value
+				problems = append(problems, validateCertificate(cfg.CA, name, cfg.CA.Profiles[name], "", value.Content[j], value.Content[j+1])...)
+			}
+			continue
+		}
+
+		var slot model.Certificate
+		switch constants.CertType(key.Value) {
+		case constants.CERT_TYPE_ROOT:
+			slot = cfg.CA.Root
+		case constants.CERT_TYPE_INTERMEDIATE:
+			slot = cfg.CA.Intermediate
+		case constants.CERT_TYPE_SERVER:
+			slot = cfg.CA.Server
+		case constants.CERT_TYPE_CLIENT:
+			slot = cfg.CA.Client
+		case constants.CERT_TYPE_PEER:
+			slot = cfg.CA.Peer
+		default:
+			// reported by the strict decoding
+			continue
+		}
+		problems = append(problems, validateCertificate(cfg.CA, key.Value, slot, constants.CertType(key.Value), key, value)...)
+	}
+
+	if keyNode, hooksNode := mappingValue(root, "hooks"); keyNode != nil {
+		problems = append(problems, validateHooks(cfg, keyNode, hooksNode)...)
+	}
+	if keyNode, agentNode := mappingValue(root, "agent"); keyNode != nil {
+		problems = append(problems, validateAgent(cfg, keyNode, agentNode)...)
+	}
+
+	// unknown keys are reported by line only, find the certificate they belong to
+	lineProfile := make(map[int]string)
+	for i := 0; i+1 < len(caNode.Content); i += 2 {
+		key, value := caNode.Content[i], caNode.Content[i+1]
+		certs := [][2]*yaml.Node{{key, value}}
+		if key.Value == "profiles" && value.Kind == yaml.MappingNode {
+			certs = certs[:0]
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				certs = append(certs, [2]*yaml.Node{value.Content[j], value.Content[j+1]})
+			}
+		}
+		for _, c := range certs {
+			if c[1].Kind != yaml.MappingNode {
+				continue
+			}
+			for j := 0; j < len(c[1].Content); j += 2 {
+				lineProfile[c[1].Content[j].Line] = c[0].Value
+			}
+		}
+	}
+	for _, section := range []string{"hooks", "agent"} {
+		if _, sectionNode := mappingValue(root, section); sectionNode != nil {
+			markSectionLines(sectionNode, section, lineProfile)
+		}
+	}
+	for i := range problems {
+		if problems[i].Profile == "" {
+			problems[i].Profile = lineProfile[problems[i].Line]
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+	return problems, nil
+}
+
+// validateCertificate returns the problems of the certificate cfg named name, decoded from the
+// mapping node whose key is keyNode. slotType is the type a slot certificate defaults to.
+func validateCertificate(ca model.CertificateAuthority, name string, cfg model.Certificate, slotType constants.CertType, keyNode, node *yaml.Node) []ConfigProblem {
+	var problems []ConfigProblem
+	report := func(field, format string, args ...interface{}) {
+		line := keyNode.Line
+		if key, _ := mappingValue(node, field); key != nil {
+			line = key.Line
+		}
+		problems = append(problems, ConfigProblem{Line: line, Profile: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	certType := constants.CertType(cfg.Type)
+	switch certType {
+	case "":
+		certType = slotType
+		if certType == "" && cfg.Issuer == "" && cfg.ParentCertPath == "" {
+			certType = constants.CERT_TYPE_ROOT
+		}
+	case constants.CERT_TYPE_ROOT, constants.CERT_TYPE_INTERMEDIATE, constants.CERT_TYPE_SERVER, constants.CERT_TYPE_CLIENT, constants.CERT_TYPE_PEER:
+	default:
+		report("type", "invalid type %q, expected root, intermediate, server, client or peer", cfg.Type)
+	}
+
+	if cfg.KeyType != "" {
+		if _, err := util.ParsePrivateKeyType(cfg.KeyType); err != nil {
+			report("key_type", "%v", err)
+		}
+	}
+
+	if cfg.CertFilePath == "" {
+		report("cert", "cert is required")
+	}
+	if cfg.KeyFilePath == "" {
+		report("private_key", "private_key is required")
+	}
+
+	// an invalid validity is reported once, not as a missing one
+	invalid := false
+	for _, v := range []struct {
+		field string
+		value int
+	}{{"validity_years", cfg.ValidityYears}, {"validity_month", cfg.ValidityMonth}, {"validity_day", cfg.ValidityDay}} {
+		if v.value < 0 {
+			report(v.field, "%s cannot be negative", v.field)
+			invalid = true
+		}
+	}
+	var validity time.Duration
+	if cfg.Validity != "" {
+		d, err := util.ParseDuration(cfg.Validity)
+		if err != nil {
+			report("validity", "%v", err)
+			invalid = true
+		}
+		validity = d
+	}
+	if cfg.Backdate != "" {
+		if _, err := util.ParseDuration(cfg.Backdate); err != nil {
+			report("backdate", "%v", err)
+		}
+	}
+	var notBefore, notAfter time.Time
+	if cfg.NotBefore != "" {
+		t, err := util.ParseTimestamp(cfg.NotBefore)
+		if err != nil {
+			report("not_before", "%v", err)
+		}
+		notBefore = t
+	}
+	if cfg.NotAfter != "" {
+		t, err := util.ParseTimestamp(cfg.NotAfter)
+		if err != nil {
+			report("not_after", "%v", err)
+		}
+		notAfter = t
+	}
+	switch {
+	case !notBefore.IsZero() && !notAfter.IsZero() && !notAfter.After(notBefore):
+		report("not_after", "not_after must be after not_before")
+	case !invalid && cfg.NotAfter == "" && validity == 0 && cfg.ValidityYears == 0 && cfg.ValidityMonth == 0 && cfg.ValidityDay == 0:
+		report("validity_years", "validity must be positive, set validity_years, validity_month, validity_day, validity or not_after")
+	}
+
+	if certType != constants.CERT_TYPE_ROOT {
+		if cfg.CsrFilePath == "" {
+			report("csr", "csr is required for %s certificates", typeName(certType))
+		}
+		if cfg.Issuer != "" {
+			if issuer, ok := ca.Profile(cfg.Issuer); !ok {
+				report("issuer", "issuer profile not found: %s", cfg.Issuer)
+			} else if cfg.Issuer == name {
+				report("issuer", "profile cannot be its own issuer")
+			} else if !issuer.IsCA {
+				report("issuer", "issuer profile %s is not a CA", cfg.Issuer)
+			}
+		} else {
+			if cfg.ParentCertPath == "" {
+				report("parent_cert", "parent_cert or issuer is required for %s certificates", typeName(certType))
+			}
+			if cfg.ParentKeyPath == "" {
+				report("parent_key", "parent_key or issuer is required for %s certificates", typeName(certType))
+			}
+		}
+	}
+
+	switch certType {
+	case constants.CERT_TYPE_ROOT, constants.CERT_TYPE_INTERMEDIATE:
+		if !cfg.IsCA {
+			report("is_ca", "is_ca must be true for %s certificates", certType)
+		}
+	case constants.CERT_TYPE_SERVER, constants.CERT_TYPE_CLIENT, constants.CERT_TYPE_PEER:
+		if cfg.IsCA {
+			report("is_ca", "is_ca must be false for %s certificates", certType)
+		}
+	}
+
+	if _, err := util.ParseKeyUsage(cfg.KeyUsageNames); err != nil {
+		report("key_usage", "%v", err)
+	}
+	if _, _, err := util.ParseExtKeyUsage(cfg.ExtKeyUsageNames); err != nil {
+		report("ext_key_usage", "%v", err)
+	}
+
+	if cfg.Policy != nil {
+		if !cfg.IsCA {
+			report("policy", "policy is only valid on CAs")
+		}
+		for _, problem := range validatePolicy(*cfg.Policy) {
+			report("policy", "policy: %s", problem)
+		}
+	}
+
+	for _, dnsName := range cfg.DNSNames {
+		if !validDNSName(dnsName) {
+			report("dns_names", "invalid dns name %q", dnsName)
+		}
+	}
+	for _, ip := range cfg.IPAddresses {
+		if net.ParseIP(ip) == nil {
+			report("ip_addresses", "invalid ip address %q", ip)
+		}
+	}
+	for _, uri := range cfg.URIs {
+		if u, err := url.Parse(uri); err != nil || u.Scheme == "" || strings.ContainsAny(uri, " \t") {
+			report("uris", "invalid uri %q, expected a scheme like spiffe://example.com/ns/prod", uri)
+		}
+	}
+
+	return problems
+}
+
+// validateAgent returns the problems of the agent section decoded from the mapping node whose key is keyNode.
+func validateAgent(cfg model.CAConfig, keyNode, node *yaml.Node) []ConfigProblem {
+	var problems []ConfigProblem
+	report := func(n *yaml.Node, field, format string, args ...interface{}) {
+		line := keyNode.Line
+		if n != nil && n != node {
+			line = n.Line
+		}
+		if key, _ := mappingValue(n, field); key != nil {
+			line = key.Line
+		}
+		problems = append(problems, ConfigProblem{Line: line, Profile: "agent", Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, d := range []struct {
+		field string
+		value string
+	}{{"interval", cfg.Agent.Interval}, {"jitter", cfg.Agent.Jitter}, {"renew_before", cfg.Agent.RenewBefore}} {
+		if d.value == "" {
+			continue
+		}
+		if v, err := util.ParseDayDuration(d.value); err != nil {
+			report(node, d.field, "%v", err)
+		} else if v == 0 && d.field == "interval" {
+			report(node, d.field, "interval must be positive")
+		}
+	}
+	if cfg.Agent.HealthAddr != "" {
+		if err := validHealthAddr(cfg.Agent.HealthAddr); err != nil {
+			report(node, "health_addr", "%v", err)
+		}
+	}
+
+	_, hooksNode := mappingValue(node, "hooks")
+	for i, hook := range cfg.Agent.Hooks {
+		var hookNode *yaml.Node
+		if hooksNode != nil && i < len(hooksNode.Content) {
+			hookNode = hooksNode.Content[i]
+		}
+		if (len(hook.Exec) == 0) == (hook.SignalPidFile == "") {
+			report(hookNode, "exec", "hook %d must set exactly one of exec or signal_pid_file", i+1)
+		}
+		for _, name := range hook.Certificates {
+			if _, ok := cfg.CA.Profile(name); !ok {
+				report(hookNode, "certificates", "hook %d: certificate profile not found: %s", i+1, name)
+			}
+		}
+	}
+	return problems
+}
+
+// validateHooks returns the problems of the hooks section decoded from the sequence node whose key is keyNode.
+func validateHooks(cfg model.CAConfig, keyNode, node *yaml.Node) []ConfigProblem {
+	var problems []ConfigProblem
+	for i, hook := range cfg.Hooks {
+		hookNode := keyNode
+		if node != nil && node.Kind == yaml.SequenceNode && i < len(node.Content) {
+			hookNode = node.Content[i]
+		}
+		report := func(field, format string, args ...interface{}) {
+			line := hookNode.Line
+			if key, _ := mappingValue(hookNode, field); key != nil {
+				line = key.Line
+			}
+			problems = append(problems, ConfigProblem{Line: line, Profile: "hooks", Message: fmt.Sprintf("hook %d: "+format, append([]interface{}{i + 1}, args...)...)})
+		}
+
+		if len(hook.Events) == 0 {
+			report("events", "events is required")
+		}
+		for _, event := range hook.Events {
+			switch constants.HookEvent(event) {
+			case constants.HOOK_EVENT_KEY_CREATED, constants.HOOK_EVENT_CSR_CREATED, constants.HOOK_EVENT_CERT_ISSUED, constants.HOOK_EVENT_CERT_RENEWED:
+			default:
+				report("events", "unknown event %q, expected key_created, csr_created, cert_issued or cert_renewed", event)
+			}
+		}
+		for _, name := range hook.Certificates {
+			if _, ok := cfg.CA.Profile(name); !ok {
+				report("certificates", "certificate profile not found: %s", name)
+			}
+		}
+		if (len(hook.Exec) == 0) == (hook.HTTPPost == "") {
+			report("exec", "exactly one of exec or http_post must be set")
+		}
+		for _, arg := range hook.Exec {
+			if _, err := template.New("arg").Parse(arg); err != nil {
+				report("exec", "invalid template %q", arg)
+			}
+		}
+		if hook.HTTPPost != "" {
+			if err := validHookURL(hook.HTTPPost); err != nil {
+				report("http_post", "%v", err)
+			}
+		}
+		if hook.Timeout != "" {
+			if _, err := util.ParseDuration(hook.Timeout); err != nil {
+				report("timeout", "%v", err)
+			}
+		}
+		switch constants.HookFailure(hook.OnFailure) {
+		case "", constants.HOOK_FAILURE_IGNORE, constants.HOOK_FAILURE_ABORT:
+		default:
+			report("on_failure", "invalid on_failure %q, expected ignore or abort", hook.OnFailure)
+		}
+	}
+	return problems
+}
+
+// markSectionLines maps the lines of the keys of a top level section, at any depth, to the section.
+func markSectionLines(node *yaml.Node, section string, lineProfile map[int]string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			lineProfile[node.Content[i].Line] = section
+			markSectionLines(node.Content[i+1], section, lineProfile)
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			markSectionLines(item, section, lineProfile)
+		}
+	}
+}
+
+func typeName(certType constants.CertType) string {
+	if certType == "" {
+		return "non-root"
+	}
+	return string(certType)
+}
+
+// validDNSName reports whether name is a host name, optionally with a wildcard left-most label.
+func validDNSName(name string) bool {
+	name = strings.TrimPrefix(name, "*.")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// decodeProblem turns an error of the strict decoding, "line N: message", into a problem.
+func decodeProblem(msg string) ConfigProblem {
+	var problem ConfigProblem
+	if _, err := fmt.Sscanf(msg, "line %d:", &problem.Line); err == nil {
+		msg = strings.TrimSpace(msg[strings.Index(msg, ":")+1:])
+	}
+
+	var field, typ string
+	if _, err := fmt.Sscanf(msg, "field %s not found in type %s", &field, &typ); err == nil {
+		msg = fmt.Sprintf("unknown key %q", field)
+	}
+	problem.Message = msg
+	return problem
+}
+
+// mappingValue returns the key and value nodes of key in the mapping node, nil if there is none.
+func mappingValue(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
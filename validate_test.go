@@ -0,0 +1,221 @@
+package certgo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateConfigFile(t *testing.T) {
+	problems, err := ValidateConfigFile("./defaultCfg.yml")
+	if err != nil {
+		t.Fatalf("TestValidateConfigFile: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Fatalf("TestValidateConfigFile: unexpected problems %v", problems)
+	}
+}
+
+var testCaseValidateConfig = []struct {
+	name   string
+	yaml   string
+	expect []ConfigProblem
+}{
+	{
+		name: "unknown key",
+		yaml: `ca:
+  root:
+    cert: root.cert.pem
+    private_key: root.key.pem
+    is_ca: true
+    validity_year: 10
+`,
+		expect: []ConfigProblem{
+			{Line: 2, Profile: "root", Message: "validity must be positive, set validity_years, validity_month, validity_day, validity or not_after"},
+			{Line: 6, Profile: "root", Message: `unknown key "validity_year"`},
+		},
+	},
+	{
+		name: "non-root without parent",
+		yaml: `ca:
+  server:
+    cert: server.cert.pem
+    private_key: server.key.pem
+    csr: server.csr.pem
+    is_ca: true
+    key_type: ecdh
+    validity_years: 1
+    dns_names: ["localhost", "bad host"]
+    ip_addresses: ["127.0.0.300"]
+    uris: ["spiffe://example.com/ns/prod", "example.com/ns/prod"]
+`,
+		expect: []ConfigProblem{
+			{Line: 2, Profile: "server", Message: "parent_cert or issuer is required for server certificates"},
+			{Line: 2, Profile: "server", Message: "parent_key or issuer is required for server certificates"},
+			{Line: 6, Profile: "server", Message: "is_ca must be false for server certificates"},
+			{Line: 7, Profile: "server", Message: `unknown private key type: "ecdh", expected ecdsa or rsa`},
+			{Line: 9, Profile: "server", Message: `invalid dns name "bad host"`},
+			{Line: 10, Profile: "server", Message: `invalid ip address "127.0.0.300"`},
+			{Line: 11, Profile: "server", Message: `invalid uri "example.com/ns/prod", expected a scheme like spiffe://example.com/ns/prod`},
+		},
+	},
+	{
+		name: "profile issuer",
+		yaml: `ca:
+  profiles:
+    kafka-ca:
+      type: intermediate
+      cert: kafka-ca.cert.pem
+      private_key: kafka-ca.key.pem
+      csr: kafka-ca.csr.pem
+      issuer: root
+      validity_years: 1
+    kafka-broker:
+      type: broker
+      cert: kafka-broker.cert.pem
+      private_key: kafka-broker.key.pem
+      csr: kafka-broker.csr.pem
+      issuer: kafka-broker
+      validity_month: -1
+      ext_key_usage: [serverAuth, mining]
+`,
+		expect: []ConfigProblem{
+			{Line: 3, Profile: "kafka-ca", Message: "is_ca must be true for intermediate certificates"},
+			{Line: 8, Profile: "kafka-ca", Message: "issuer profile root is not a CA"},
+			{Line: 11, Profile: "kafka-broker", Message: `invalid type "broker", expected root, intermediate, server, client or peer`},
+			{Line: 15, Profile: "kafka-broker", Message: "profile cannot be its own issuer"},
+			{Line: 16, Profile: "kafka-broker", Message: "validity_month cannot be negative"},
+			{Line: 17, Profile: "kafka-broker", Message: "unknown extended key usage: mining"},
+		},
+	},
+	{
+		name: "validity window",
+		yaml: `ca:
+  root:
+    cert: root.cert.pem
+    private_key: root.key.pem
+    is_ca: true
+    not_before: 2030-01-01
+    not_after: 2029-01-01T00:00:00Z
+    backdate: -5m
+`,
+		expect: []ConfigProblem{
+			{Line: 7, Profile: "root", Message: "not_after must be after not_before"},
+			{Line: 8, Profile: "root", Message: `invalid duration: "-5m", cannot be negative`},
+		},
+	},
+	{
+		name: "agent",
+		yaml: `ca:
+  root:
+    cert: root.cert.pem
+    private_key: root.key.pem
+    is_ca: true
+    validity_years: 10
+agent:
+  interval: 0s
+  renew_before: 30d
+  health_addr: 0.0.0.0:8787
+  hooks:
+    - certificates: [root, unknown]
+      exec: [systemctl, reload, nginx]
+    - signal_pid_file: nginx.pid
+      exec: [true]
+      timeout: 1m
+`,
+		expect: []ConfigProblem{
+			{Line: 8, Profile: "agent", Message: "interval must be positive"},
+			{Line: 10, Profile: "agent", Message: `invalid health address: "0.0.0.0:8787", the host must be localhost or a loopback address`},
+			{Line: 12, Profile: "agent", Message: "hook 1: certificate profile not found: unknown"},
+			{Line: 15, Profile: "agent", Message: "hook 2 must set exactly one of exec or signal_pid_file"},
+			{Line: 16, Profile: "agent", Message: `unknown key "timeout"`},
+		},
+	},
+	{
+		name: "hooks",
+		yaml: `hooks:
+  - events: [cert_issued, cert_expired]
+    exec: [cp, "{{.CertPath", /etc/nginx/]
+    on_failure: retry
+  - events: [key_created]
+    http_post: https://hooks.example.com/cert
+    timeout: 10s
+    certificates: [web]
+ca:
+  root:
+    cert: root.cert.pem
+    private_key: root.key.pem
+    is_ca: true
+    validity_years: 10
+`,
+		expect: []ConfigProblem{
+			{Line: 2, Profile: "hooks", Message: `hook 1: unknown event "cert_expired", expected key_created, csr_created, cert_issued or cert_renewed`},
+			{Line: 3, Profile: "hooks", Message: `hook 1: invalid template "{{.CertPath"`},
+			{Line: 4, Profile: "hooks", Message: `hook 1: invalid on_failure "retry", expected ignore or abort`},
+			{Line: 6, Profile: "hooks", Message: `hook 2: invalid http_post url: "https://hooks.example.com/cert", the host must be localhost or a loopback address`},
+			{Line: 8, Profile: "hooks", Message: "hook 2: certificate profile not found: web"},
+		},
+	},
+	{
+		name: "policy",
+		yaml: `ca:
+  root:
+    cert: root.cert.pem
+    private_key: root.key.pem
+    is_ca: true
+    validity_years: 10
+    policy:
+      dns_suffixes: [example.com, "*.example.org"]
+      ip_ranges: [10.0.0.0]
+      uri_prefixes: [example.com/ns]
+      email_domains: ["@example.com"]
+      max_validity: 90days
+      key_algorithms: [ed448]
+      min_key_bits: {rsa: 3072, dsa: 2048}
+      required_subject: [email]
+      ext_key_usage: [webAuth]
+  server:
+    cert: server.cert.pem
+    private_key: server.key.pem
+    csr: server.csr.pem
+    parent_cert: root.cert.pem
+    parent_key: root.key.pem
+    validity_years: 1
+    policy:
+      max_validity: 90d
+`,
+		expect: []ConfigProblem{
+			{Line: 7, Profile: "root", Message: `policy: invalid dns suffix "*.example.org"`},
+			{Line: 7, Profile: "root", Message: `policy: invalid ip range "10.0.0.0", expected a CIDR like 10.0.0.0/8`},
+			{Line: 7, Profile: "root", Message: `policy: invalid uri prefix "example.com/ns", expected a scheme, a host and an optional path like spiffe://example.com/ns/prod`},
+			{Line: 7, Profile: "root", Message: `policy: invalid email domain "@example.com"`},
+			{Line: 7, Profile: "root", Message: `policy: invalid max_validity "90days", expected a number of days like 90d or a duration like 2160h`},
+			{Line: 7, Profile: "root", Message: `policy: invalid key algorithm "ed448", expected ecdsa or rsa`},
+			{Line: 7, Profile: "root", Message: `policy: invalid key algorithm "dsa" in min_key_bits, expected ecdsa or rsa`},
+			{Line: 7, Profile: "root", Message: `policy: unknown subject field "email"`},
+			{Line: 7, Profile: "root", Message: "policy: unknown extended key usage: webAuth"},
+			{Line: 24, Profile: "server", Message: "policy is only valid on CAs"},
+		},
+	},
+	{
+		name: "missing ca",
+		yaml: "certificate_authority: {}\n",
+		expect: []ConfigProblem{
+			{Line: 0, Message: "missing ca section"},
+			{Line: 1, Message: `unknown key "certificate_authority"`},
+		},
+	},
+}
+
+func TestValidateConfig(t *testing.T) {
+	for _, testCase := range testCaseValidateConfig {
+		t.Run(testCase.name, func(t *testing.T) {
+			problems, err := ValidateConfig([]byte(testCase.yaml))
+			if err != nil {
+				t.Fatalf("TestValidateConfig (%s): %v", testCase.name, err)
+			}
+			if !reflect.DeepEqual(problems, testCase.expect) {
+				t.Fatalf("TestValidateConfig (%s): actual %v != expect %v", testCase.name, problems, testCase.expect)
+			}
+		})
+	}
+}